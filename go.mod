@@ -4,10 +4,13 @@ go 1.15
 
 require (
 	github.com/Masterminds/semver/v3 v3.1.0
+	github.com/go-git/go-git/v5 v5.2.0
+	github.com/klauspost/compress v1.11.13
 	github.com/kudobuilder/kudo v0.17.0
 	github.com/sirupsen/logrus v1.7.0
 	github.com/spf13/afero v1.4.1
 	github.com/spf13/cobra v1.0.0
 	github.com/stretchr/testify v1.6.1
 	gopkg.in/yaml.v2 v2.3.0
+	gopkg.in/yaml.v3 v3.0.1
 )
@@ -28,6 +28,72 @@ type GitSource struct {
 	Name string `yaml:"name"`
 
 	URL string `yaml:"url"`
+
+	// Auth references the credentials used to access the repository.
+	// If not set, credentials of the host are used.
+	Auth *GitAuth `yaml:"auth,omitempty"`
+
+	// Submodules enables checking out the submodules of the operator
+	// directory recursively. They are only accessed with the credentials of
+	// 'Auth' if they are on the same host as 'URL'. Submodules with "file://"
+	// URLs or local paths are rejected.
+	Submodules bool `yaml:"submodules,omitempty"`
+
+	// LFS enables replacing Git LFS pointers with the content of their
+	// objects. It needs the "exec" Git backend and an installed 'git-lfs'.
+	LFS bool `yaml:"lfs,omitempty"`
+}
+
+// GitAuth references credentials of a Git repository. Secrets are never set
+// inline, they are read from environment variables or files.
+type GitAuth struct {
+	// Username for HTTP basic authentication.
+	Username string `yaml:"username,omitempty"`
+
+	// Password or access token for HTTP basic authentication.
+	Password *Credential `yaml:"password,omitempty"`
+
+	// SSHKey is the path of a private SSH key for SSH URLs.
+	SSHKey string `yaml:"sshKey,omitempty"`
+}
+
+// URLAuth references credentials and headers of HTTP requests. Secrets are
+// never set inline, they are read from environment variables or files.
+type URLAuth struct {
+	// Username for HTTP basic authentication.
+	Username string `yaml:"username,omitempty"`
+
+	// Password for HTTP basic authentication.
+	Password *Credential `yaml:"password,omitempty"`
+
+	// BearerToken is sent in an "Authorization: Bearer" header. It takes
+	// precedence over basic authentication.
+	BearerToken *Credential `yaml:"bearerToken,omitempty"`
+
+	// Headers are additional headers of the requests.
+	Headers []Header `yaml:"headers,omitempty"`
+}
+
+// Header is an HTTP header. Either 'Value' or 'ValueFrom' has to be set.
+type Header struct {
+	// Name of the header.
+	Name string `yaml:"name"`
+
+	// Value of the header, for values that aren't secret.
+	Value string `yaml:"value,omitempty"`
+
+	// ValueFrom references a secret value of the header.
+	ValueFrom *Credential `yaml:"valueFrom,omitempty"`
+}
+
+// Credential references a secret. Exactly one of its fields has to be set.
+type Credential struct {
+	// Env is the name of an environment variable containing the secret.
+	Env string `yaml:"env,omitempty"`
+
+	// File is the path of a file containing the secret. Trailing newlines are
+	// ignored.
+	File string `yaml:"file,omitempty"`
 }
 
 // Version describes a version of a KUDO operator.
@@ -44,21 +110,65 @@ type Version struct {
 
 	// URL specifies a version as a URL of a package tarball.
 	URL *string `yaml:"url,omitempty"`
+
+	// SHA256 is the hex encoded SHA-256 checksum of the tarball at 'URL',
+	// optional. If set, the downloaded tarball has to match it.
+	SHA256 string `yaml:"sha256,omitempty"`
+
+	// Subdirectory of the archive at 'URL' containing the operator package,
+	// optional. It is looked up at the top level of the archive first and,
+	// if it doesn't exist there, in its only top-level directory if all files
+	// are wrapped in one.
+	Subdirectory string `yaml:"subdirectory,omitempty"`
+
+	// URLAuth references credentials and headers of the requests for 'URL'.
+	// If not set, credentials configured for the host of 'URL' are used.
+	URLAuth *URLAuth `yaml:"urlAuth,omitempty"`
+
+	// Path specifies a version as a local directory containing the operator
+	// package. Relative paths are relative to the directory of the file
+	// containing this version.
+	Path *string `yaml:"path,omitempty"`
+
+	// OCI specifies a version as an artifact in an OCI registry, referenced
+	// as "oci://registry/namespace/name:tag" or by digest.
+	OCI *string `yaml:"oci,omitempty"`
 }
 
-// Git references a specific tag of a Git repository of a KUDO operator.
+// Git references a specific tag, branch or commit of a Git repository of a
+// KUDO operator.
 type Git struct {
 	// Source references a 'GitSource' name. The source's Git repository is
-	// cloned and the specified tag is checked out.
+	// cloned and the specified revision is checked out.
 	Source string `yaml:"source"`
 
 	// Directory where the KUDO operator is defined in the Git repository.
 	Directory string `yaml:"directory"`
 
-	// Tag of the KUDO operator version. Either this or 'SHA' has to be set.
+	// Tag of the KUDO operator version. Either this, 'Branch', 'SHA' or
+	// 'TagPattern' has to be set. If both this and 'SHA' are set, the tag has
+	// to point to the commit 'SHA'.
 	Tag string `yaml:"tag,omitempty"`
 
-	// SHA of the KUDO operator version if a branch is used instead of
-	// a tag. Either this or 'Tag' has to be set.
+	// Branch of the KUDO operator version. The commit the branch currently
+	// points to is used, which makes references to branches not reproducible.
+	// It can't be set together with 'Tag'.
+	Branch string `yaml:"branch,omitempty"`
+
+	// SHA of the KUDO operator version if neither a tag nor a branch is used.
+	// If 'Tag' or 'Branch' is set as well, 'SHA' pins the commit they are
+	// expected to point to and has to be a full 40 character SHA.
 	SHA string `yaml:"sha,omitempty"`
+
+	// TagPattern makes the version a template for all tags of the repository
+	// matching this regular expression, instead of referencing a single
+	// revision. The expression has to match the whole tag. Its named groups
+	// "operatorVersion" and "appVersion" capture the versions of a tag.
+	// If the operator version is neither captured nor set, it and the app
+	// version are read from the operator package of the tag.
+	TagPattern string `yaml:"tagPattern,omitempty"`
+
+	// Exclude lists tags matching 'TagPattern' that aren't versions of the
+	// KUDO operator.
+	Exclude []string `yaml:"exclude,omitempty"`
 }
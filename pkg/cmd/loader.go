@@ -0,0 +1,38 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/Masterminds/semver/v3"
+	"github.com/spf13/cobra"
+
+	"github.com/kudobuilder/kitt/pkg/internal/resolver"
+	"github.com/kudobuilder/kitt/pkg/loader"
+)
+
+// loaderFlags adds the flags configuring how operators are loaded to 'cmd'.
+// The returned function creates a loader of the operator files 'paths' once
+// the flags have been parsed. Versions are discovered from Git tags with the
+// sources of 'cache'.
+func loaderFlags(
+	cmd *cobra.Command,
+) func(ctx context.Context, paths []string, cache *resolver.Cache) (loader.OperatorLoader, error) {
+	since := cmd.Flags().String(
+		"since", "", "only discover versions from Git tags with at least this operator version")
+
+	return func(ctx context.Context, paths []string, cache *resolver.Cache) (loader.OperatorLoader, error) {
+		var sinceVersion *semver.Version
+
+		if *since != "" {
+			v, err := semver.NewVersion(*since)
+			if err != nil {
+				return nil, fmt.Errorf("invalid --since version %q: %v", *since, err)
+			}
+
+			sinceVersion = v
+		}
+
+		return loader.WithDiscoveredVersions(ctx, loader.FromFiles(paths), cache, sinceVersion), nil
+	}
+}
@@ -0,0 +1,31 @@
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+
+	"github.com/kudobuilder/kitt/pkg/lock"
+)
+
+func lockCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "lock operator.yaml...",
+		Args:  cobra.MinimumNArgs(1),
+		Short: "Pin Git tags of operator references to their commit SHAs",
+		Long: `Git tags are mutable. kitt resolves the commit each Git tag reference currently
+points to and writes its SHA into the 'sha' field of the reference. Resolving
+a locked reference fails if its tag has been moved to another commit.`,
+	}
+
+	resolverOptions := resolverFlags(cmd)
+
+	cmd.RunE = func(cmd *cobra.Command, args []string) error {
+		options, err := resolverOptions()
+		if err != nil {
+			return err
+		}
+
+		return lock.Lock(cmd.Context(), args, options)
+	}
+
+	return cmd
+}
@@ -0,0 +1,61 @@
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+
+	"github.com/kudobuilder/kitt/pkg/internal/resolver"
+	"github.com/kudobuilder/kitt/pkg/loader"
+	"github.com/kudobuilder/kitt/pkg/prune"
+)
+
+func pruneCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "prune operator.yaml...",
+		Args:  cobra.MinimumNArgs(1),
+		Short: "Remove operators from a repository that are no longer referenced",
+		Long: `The provided operator references describe the desired state of a repository.
+kitt resolves all references and removes all operator package tarballs and their
+index entries from the repository whose operator name and version doesn't match
+any referenced package. Nothing is removed if a reference can't be resolved.`,
+	}
+
+	dryRun := cmd.Flags().Bool("dry-run", false, "only list the operators that would be removed")
+
+	repoPath := cmd.Flags().String("repository", ".", "path to the operator repository")
+
+	if err := cmd.MarkFlagDirname("repository"); err != nil {
+		panic(err)
+	}
+
+	repoURL := cmd.Flags().String("repository_url", "", "URL of the operator repository to set in \"index.yaml\"")
+
+	resolverOptions := resolverFlags(cmd)
+
+	parallelism := cmd.Flags().Int("parallelism", 1, "number of operator versions to resolve concurrently")
+
+	cmd.RunE = func(cmd *cobra.Command, args []string) error {
+		options, err := resolverOptions()
+		if err != nil {
+			return err
+		}
+
+		return withResolverCache(options, func(cache *resolver.Cache) error {
+			// All versions discovered from Git tags are referenced, there's no
+			// '--since' cutoff, so that versions added by earlier updates are
+			// kept.
+			operators := loader.WithDiscoveredVersions(cmd.Context(), loader.FromFiles(args), cache, nil)
+
+			return prune.Prune(
+				cmd.Context(),
+				operators,
+				*repoPath,
+				*repoURL,
+				cache,
+				*parallelism,
+				*dryRun,
+				cmd.OutOrStdout())
+		})
+	}
+
+	return cmd
+}
@@ -0,0 +1,114 @@
+package cmd
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/spf13/afero"
+	"github.com/spf13/cobra"
+
+	"github.com/kudobuilder/kitt/pkg/internal/config"
+	"github.com/kudobuilder/kitt/pkg/internal/resolver"
+	"github.com/kudobuilder/kitt/pkg/internal/resolver/archive"
+	"github.com/kudobuilder/kitt/pkg/internal/resolver/url"
+)
+
+// withResolverCache runs 'f' with a resolver cache configured by 'options'.
+// The cache is shared by version discovery and resolving, so that each source
+// is only retrieved once, and removed once 'f' returns.
+func withResolverCache(options resolver.Options, f func(cache *resolver.Cache) error) (err error) {
+	cache, err := resolver.NewCache(options)
+	if err != nil {
+		return fmt.Errorf("failed to create resolver cache: %v", err)
+	}
+
+	defer func() {
+		if rerr := cache.Remove(); rerr != nil && err == nil {
+			err = fmt.Errorf("failed to remove resolver cache: %v", rerr)
+		}
+	}()
+
+	return f(cache)
+}
+
+// resolverFlags adds the flags configuring how sources are retrieved to 'cmd'.
+// The returned function creates resolver options from the flags once they
+// have been parsed.
+func resolverFlags(cmd *cobra.Command) func() (resolver.Options, error) {
+	cacheDir := cmd.Flags().String(
+		"cache-dir", "", "path to a directory caching Git repositories and tarballs between runs")
+
+	if err := cmd.MarkFlagDirname("cache-dir"); err != nil {
+		panic(err)
+	}
+
+	gitBackend := cmd.Flags().String(
+		"git-backend", "exec", "Git implementation, either \"exec\" to run the git binary or \"go\" to use a built-in one")
+
+	configFile := cmd.Flags().String(
+		"config", "", "path to a kitt config file with credentials of URL sources and OCI registries per host")
+
+	if err := cmd.MarkFlagFilename("config", "yaml", "yml"); err != nil {
+		panic(err)
+	}
+
+	httpConnectTimeout := cmd.Flags().Duration(
+		"http-connect-timeout", 30*time.Second, "timeout of connecting to hosts of URL sources, 0 for no timeout")
+
+	httpReadTimeout := cmd.Flags().Duration(
+		"http-read-timeout", time.Minute, "timeout of waiting for data from hosts of URL sources, 0 for no timeout")
+
+	httpRetries := cmd.Flags().Int(
+		"http-retries", 3, "number of retries of URL downloads failing with network or server errors")
+
+	httpProxy := cmd.Flags().String(
+		"http-proxy", "", "URL of a proxy for URL sources, defaults to the HTTPS_PROXY and HTTP_PROXY variables")
+
+	httpCABundle := cmd.Flags().String(
+		"http-ca-bundle", "", "path to a PEM file of CA certificates to trust for URL sources, in addition to the system's")
+
+	if err := cmd.MarkFlagFilename("http-ca-bundle", "pem", "crt"); err != nil {
+		panic(err)
+	}
+
+	limits := archive.DefaultLimits()
+
+	maxArchiveSize := cmd.Flags().Int64(
+		"max-archive-size", limits.TotalSize, "maximum size in bytes of the files of a package tarball, 0 for no limit")
+
+	maxArchiveEntries := cmd.Flags().Int(
+		"max-archive-entries", limits.Entries, "maximum number of entries of a package tarball, 0 for no limit")
+
+	maxArchiveFileSize := cmd.Flags().Int64(
+		"max-archive-file-size", limits.FileSize, "maximum size in bytes of a file in a package tarball, 0 for no limit")
+
+	return func() (resolver.Options, error) {
+		options := resolver.Options{
+			CacheDir:   *cacheDir,
+			GitBackend: *gitBackend,
+			HTTP: url.ClientOptions{
+				ConnectTimeout: *httpConnectTimeout,
+				ReadTimeout:    *httpReadTimeout,
+				Retries:        *httpRetries,
+				Proxy:          *httpProxy,
+				CABundle:       *httpCABundle,
+			},
+			Archive: archive.Limits{
+				TotalSize: *maxArchiveSize,
+				Entries:   *maxArchiveEntries,
+				FileSize:  *maxArchiveFileSize,
+			},
+		}
+
+		if *configFile != "" {
+			cfg, err := config.FromFile(afero.NewOsFs(), *configFile)
+			if err != nil {
+				return resolver.Options{}, fmt.Errorf("failed to read config file: %v", err)
+			}
+
+			options.Config = cfg
+		}
+
+		return options, nil
+	}
+}
@@ -15,6 +15,8 @@ func New(version semver.Version) *cobra.Command {
 		SilenceUsage: true,
 	}
 
+	root.AddCommand(lockCmd())
+	root.AddCommand(pruneCmd())
 	root.AddCommand(updateCmd())
 	root.AddCommand(validateCmd())
 	root.AddCommand(versionCmd(version))
@@ -3,7 +3,8 @@ package cmd
 import (
 	"github.com/spf13/cobra"
 
-	"github.com/kudobuilder/kitt/pkg/loader"
+	"github.com/kudobuilder/kitt/pkg/internal/output"
+	"github.com/kudobuilder/kitt/pkg/internal/resolver"
 	"github.com/kudobuilder/kitt/pkg/update"
 )
 
@@ -26,8 +27,53 @@ references and creating an operator package tarball for each reference.`,
 
 	repoURL := cmd.Flags().String("repository_url", "", "URL of the operator repository to set in \"index.yaml\"")
 
+	ociRepoURL := cmd.Flags().String(
+		"oci-repository", "", "OCI registry namespace to also push operator packages to, e.g. \"oci://registry/kudo\"")
+
+	resolverOptions := resolverFlags(cmd)
+
+	operatorLoader := loaderFlags(cmd)
+
+	parallelism := cmd.Flags().Int("parallelism", 1, "number of operator versions to resolve concurrently")
+
+	dryRun := cmd.Flags().Bool("dry-run", false, "print the changes of the update without changing the repository")
+
+	outputFormat := cmd.Flags().StringP("output", "o", "text", "format of the plan of the changes, one of "+output.Formats)
+
 	cmd.RunE = func(cmd *cobra.Command, args []string) error {
-		return update.Update(cmd.Context(), loader.FromFiles(args), *repoPath, *repoURL, *force)
+		if err := output.CheckFormat(*outputFormat); err != nil {
+			return err
+		}
+
+		options, err := resolverOptions()
+		if err != nil {
+			return err
+		}
+
+		return withResolverCache(options, func(cache *resolver.Cache) error {
+			operators, err := operatorLoader(cmd.Context(), args, cache)
+			if err != nil {
+				return err
+			}
+
+			plan, err := update.Update(
+				cmd.Context(),
+				operators,
+				*repoPath,
+				*repoURL,
+				*ociRepoURL,
+				cache,
+				*parallelism,
+				*force,
+				*dryRun)
+			if err != nil {
+				return err
+			}
+
+			// Real updates report their changes as well, e.g. the commits of
+			// branches.
+			return plan.Write(cmd.OutOrStdout(), *outputFormat)
+		})
 	}
 
 	return cmd
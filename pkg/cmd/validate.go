@@ -3,7 +3,8 @@ package cmd
 import (
 	"github.com/spf13/cobra"
 
-	"github.com/kudobuilder/kitt/pkg/loader"
+	"github.com/kudobuilder/kitt/pkg/internal/output"
+	"github.com/kudobuilder/kitt/pkg/internal/resolver"
 	"github.com/kudobuilder/kitt/pkg/validate"
 )
 
@@ -17,8 +18,40 @@ operator references as well as their referenced operator packages.`,
 
 	strict := cmd.Flags().Bool("strict", false, "treat warnings as errors")
 
+	resolverOptions := resolverFlags(cmd)
+
+	operatorLoader := loaderFlags(cmd)
+
+	parallelism := cmd.Flags().Int("parallelism", 1, "number of operator versions to resolve concurrently")
+
+	outputFormat := cmd.Flags().StringP("output", "o", "text", "format of the validation report, one of "+output.Formats)
+
 	cmd.RunE = func(cmd *cobra.Command, args []string) error {
-		return validate.Validate(cmd.Context(), loader.FromFiles(args), *strict)
+		if err := output.CheckFormat(*outputFormat); err != nil {
+			return err
+		}
+
+		options, err := resolverOptions()
+		if err != nil {
+			return err
+		}
+
+		return withResolverCache(options, func(cache *resolver.Cache) error {
+			operators, err := operatorLoader(cmd.Context(), args, cache)
+			if err != nil {
+				return err
+			}
+
+			report, err := validate.Validate(cmd.Context(), operators, cache, *parallelism, *strict)
+
+			// The report is printed even if validation failed, it contains the
+			// issues that caused the failure.
+			if werr := report.Write(cmd.OutOrStdout(), *outputFormat); werr != nil && err == nil {
+				err = werr
+			}
+
+			return err
+		})
 	}
 
 	return cmd
@@ -27,8 +27,77 @@ func ConvertV1Alpha1(in v1alpha1.Operator) operator.Operator {
 
 func convertV1Alpha1GitSource(in v1alpha1.GitSource) operator.GitSource {
 	out := operator.GitSource{
-		Name: in.Name,
-		URL:  in.URL,
+		Name:       in.Name,
+		URL:        in.URL,
+		Submodules: in.Submodules,
+		LFS:        in.LFS,
+	}
+
+	if in.Auth != nil {
+		auth := convertV1Alpha1GitAuth(*in.Auth)
+		out.Auth = &auth
+	}
+
+	return out
+}
+
+func convertV1Alpha1GitAuth(in v1alpha1.GitAuth) operator.GitAuth {
+	out := operator.GitAuth{
+		Username: in.Username,
+		SSHKey:   in.SSHKey,
+	}
+
+	if in.Password != nil {
+		password := convertV1Alpha1Credential(*in.Password)
+		out.Password = &password
+	}
+
+	return out
+}
+
+// ConvertV1Alpha1URLAuth creates an internal 'URLAuth' instance from the
+// external v1alpha1 API.
+func ConvertV1Alpha1URLAuth(in v1alpha1.URLAuth) operator.URLAuth {
+	out := operator.URLAuth{
+		Username: in.Username,
+		Headers:  make([]operator.Header, len(in.Headers)),
+	}
+
+	if in.Password != nil {
+		password := convertV1Alpha1Credential(*in.Password)
+		out.Password = &password
+	}
+
+	if in.BearerToken != nil {
+		token := convertV1Alpha1Credential(*in.BearerToken)
+		out.BearerToken = &token
+	}
+
+	for i := range in.Headers {
+		out.Headers[i] = convertV1Alpha1Header(in.Headers[i])
+	}
+
+	return out
+}
+
+func convertV1Alpha1Header(in v1alpha1.Header) operator.Header {
+	out := operator.Header{
+		Name:  in.Name,
+		Value: in.Value,
+	}
+
+	if in.ValueFrom != nil {
+		value := convertV1Alpha1Credential(*in.ValueFrom)
+		out.ValueFrom = &value
+	}
+
+	return out
+}
+
+func convertV1Alpha1Credential(in v1alpha1.Credential) operator.Credential {
+	out := operator.Credential{
+		Env:  in.Env,
+		File: in.File,
 	}
 
 	return out
@@ -39,6 +108,15 @@ func convertV1Alpha1Version(in v1alpha1.Version) operator.Version {
 		OperatorVersion: in.OperatorVersion,
 		AppVersion:      in.AppVersion,
 		URL:             in.URL,
+		SHA256:          in.SHA256,
+		Subdirectory:    in.Subdirectory,
+		Path:            in.Path,
+		OCI:             in.OCI,
+	}
+
+	if in.URLAuth != nil {
+		auth := ConvertV1Alpha1URLAuth(*in.URLAuth)
+		out.URLAuth = &auth
 	}
 
 	if in.Git != nil {
@@ -51,10 +129,13 @@ func convertV1Alpha1Version(in v1alpha1.Version) operator.Version {
 
 func convertV1Alpha1Git(in v1alpha1.Git) operator.Git {
 	out := operator.Git{
-		Source:    in.Source,
-		Directory: in.Directory,
-		Tag:       in.Tag,
-		SHA:       in.SHA,
+		Source:     in.Source,
+		Directory:  in.Directory,
+		Tag:        in.Tag,
+		Branch:     in.Branch,
+		SHA:        in.SHA,
+		TagPattern: in.TagPattern,
+		Exclude:    in.Exclude,
 	}
 
 	return out
@@ -2,6 +2,7 @@ package encode
 
 import (
 	"fmt"
+	"path/filepath"
 
 	"github.com/kudobuilder/kitt/pkg/apis/operator/v1alpha1"
 	"github.com/kudobuilder/kitt/pkg/internal/apis/operator"
@@ -19,7 +20,23 @@ func FromFile(path string) (operator.Operator, error) {
 		return operator.Operator{}, err
 	}
 
-	return fromYAML(content)
+	o, err := fromYAML(content)
+	if err != nil {
+		return operator.Operator{}, err
+	}
+
+	o.File = path
+
+	// Relative paths of local operator directories are relative to the
+	// directory of the file referencing them.
+	for i := range o.Versions {
+		if o.Versions[i].Path != nil && !filepath.IsAbs(*o.Versions[i].Path) {
+			versionPath := filepath.Join(filepath.Dir(path), *o.Versions[i].Path)
+			o.Versions[i].Path = &versionPath
+		}
+	}
+
+	return o, nil
 }
 
 func fromYAML(input []byte) (operator.Operator, error) {
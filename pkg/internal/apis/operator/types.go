@@ -7,6 +7,10 @@ type Operator struct {
 	// Name of the operator.
 	Name string
 
+	// File is the path of the YAML file the operator has been read from.
+	// It is empty if the operator hasn't been read from a file.
+	File string
+
 	// GitSources are optional references to Git repositories.
 	GitSources []GitSource
 
@@ -21,6 +25,72 @@ type GitSource struct {
 	Name string
 
 	URL string
+
+	// Auth references the credentials used to access the repository.
+	// If not set, credentials of the host are used.
+	Auth *GitAuth
+
+	// Submodules enables checking out the submodules of the operator
+	// directory recursively. They are only accessed with the credentials of
+	// 'Auth' if they are on the same host as 'URL'. Submodules with "file://"
+	// URLs or local paths are rejected.
+	Submodules bool
+
+	// LFS enables replacing Git LFS pointers with the content of their
+	// objects. It needs the "exec" Git backend and an installed 'git-lfs'.
+	LFS bool
+}
+
+// GitAuth references credentials of a Git repository. Secrets are never set
+// inline, they are read from environment variables or files.
+type GitAuth struct {
+	// Username for HTTP basic authentication.
+	Username string
+
+	// Password or access token for HTTP basic authentication.
+	Password *Credential
+
+	// SSHKey is the path of a private SSH key for SSH URLs.
+	SSHKey string
+}
+
+// URLAuth references credentials and headers of HTTP requests. Secrets are
+// never set inline, they are read from environment variables or files.
+type URLAuth struct {
+	// Username for HTTP basic authentication.
+	Username string
+
+	// Password for HTTP basic authentication.
+	Password *Credential
+
+	// BearerToken is sent in an "Authorization: Bearer" header. It takes
+	// precedence over basic authentication.
+	BearerToken *Credential
+
+	// Headers are additional headers of the requests.
+	Headers []Header
+}
+
+// Header is an HTTP header. Either 'Value' or 'ValueFrom' has to be set.
+type Header struct {
+	// Name of the header.
+	Name string
+
+	// Value of the header, for values that aren't secret.
+	Value string
+
+	// ValueFrom references a secret value of the header.
+	ValueFrom *Credential
+}
+
+// Credential references a secret. Exactly one of its fields has to be set.
+type Credential struct {
+	// Env is the name of an environment variable containing the secret.
+	Env string
+
+	// File is the path of a file containing the secret. Trailing newlines are
+	// ignored.
+	File string
 }
 
 // Version describes a version of a KUDO operator.
@@ -37,6 +107,27 @@ type Version struct {
 
 	// URL specifies a version as a URL of a package tarball.
 	URL *string
+
+	// SHA256 is the hex encoded SHA-256 checksum of the tarball at 'URL',
+	// optional. If set, the downloaded tarball has to match it.
+	SHA256 string
+
+	// Subdirectory of the archive at 'URL' containing the operator package,
+	// optional. It is looked up at the top level of the archive first and,
+	// if it doesn't exist there, in its only top-level directory if all files
+	// are wrapped in one.
+	Subdirectory string
+
+	// URLAuth references credentials and headers of the requests for 'URL'.
+	// If not set, credentials configured for the host of 'URL' are used.
+	URLAuth *URLAuth
+
+	// Path specifies a version as a local directory containing the operator
+	// package.
+	Path *string
+
+	// OCI specifies a version as an artifact in an OCI registry.
+	OCI *string
 }
 
 // Version prints the version as a combination of appVersion and operatorVersion
@@ -49,19 +140,40 @@ func (v Version) Version() string {
 	return v.OperatorVersion
 }
 
-// Git references a specific tag of a Git repository of a KUDO operator.
+// Git references a specific tag, branch or commit of a Git repository of a
+// KUDO operator.
 type Git struct {
 	// Source references a 'GitSource' name. The source's Git repository is
-	// cloned and the specified tag is checked out.
+	// cloned and the specified revision is checked out.
 	Source string
 
 	// Directory where the KUDO operator is defined in the Git repository.
 	Directory string
 
-	// Tag of the KUDO operator version. Either this or 'SHA' has to be set.
+	// Tag of the KUDO operator version. Either this, 'Branch', 'SHA' or
+	// 'TagPattern' has to be set. If both this and 'SHA' are set, the tag has
+	// to point to the commit 'SHA'.
 	Tag string
 
-	// SHA of the KUDO operator version if a branch is used instead of
-	// a tag. Either this or 'Tag' has to be set.
+	// Branch of the KUDO operator version. The commit the branch currently
+	// points to is used, which makes references to branches not reproducible.
+	// It can't be set together with 'Tag'.
+	Branch string
+
+	// SHA of the KUDO operator version if neither a tag nor a branch is used.
+	// If 'Tag' or 'Branch' is set as well, 'SHA' pins the commit they are
+	// expected to point to and has to be a full 40 character SHA.
 	SHA string
+
+	// TagPattern makes the version a template for all tags of the repository
+	// matching this regular expression, instead of referencing a single
+	// revision. The expression has to match the whole tag. Its named groups
+	// "operatorVersion" and "appVersion" capture the versions of a tag.
+	// If the operator version is neither captured nor set, it and the app
+	// version are read from the operator package of the tag.
+	TagPattern string
+
+	// Exclude lists tags matching 'TagPattern' that aren't versions of the
+	// KUDO operator.
+	Exclude []string
 }
@@ -0,0 +1,87 @@
+package config
+
+import (
+	"fmt"
+
+	"github.com/spf13/afero"
+	"gopkg.in/yaml.v2"
+
+	"github.com/kudobuilder/kitt/pkg/apis/operator/v1alpha1"
+	"github.com/kudobuilder/kitt/pkg/internal/apis/operator"
+	"github.com/kudobuilder/kitt/pkg/internal/apis/operator/encode"
+)
+
+// Config of kitt.
+type Config struct {
+	// Hosts configure credentials and headers of HTTP requests per host,
+	// including requests to OCI registries.
+	Hosts []Host
+}
+
+// Host configures the requests to a host.
+type Host struct {
+	// Host matches the host of URLs, with or without a port.
+	Host string
+
+	// Auth of requests to the host.
+	Auth operator.URLAuth
+}
+
+type file struct {
+	Hosts []fileHost `yaml:"hosts,omitempty"`
+}
+
+type fileHost struct {
+	Host string `yaml:"host"`
+
+	v1alpha1.URLAuth `yaml:",inline"`
+}
+
+// FromFile reads a YAML configuration file.
+func FromFile(fs afero.Fs, path string) (Config, error) {
+	content, err := afero.ReadFile(fs, path)
+	if err != nil {
+		return Config{}, err
+	}
+
+	f := file{}
+
+	if err := yaml.UnmarshalStrict(content, &f); err != nil {
+		return Config{}, fmt.Errorf("could not decode config file %q: %v", path, err)
+	}
+
+	config := Config{
+		Hosts: make([]Host, len(f.Hosts)),
+	}
+
+	for i, host := range f.Hosts {
+		if host.Host == "" {
+			return Config{}, fmt.Errorf("host %d of config file %q has no name", i+1, path)
+		}
+
+		config.Hosts[i] = Host{
+			Host: host.Host,
+			Auth: encode.ConvertV1Alpha1URLAuth(host.URLAuth),
+		}
+	}
+
+	return config, nil
+}
+
+// Auth returns the auth of the host with name 'hostname', or of the host and
+// port 'hostport'. It returns nil if no host matches.
+func (c Config) Auth(hostport, hostname string) *operator.URLAuth {
+	for _, host := range c.Hosts {
+		if host.Host == hostport {
+			return &host.Auth
+		}
+	}
+
+	for _, host := range c.Hosts {
+		if host.Host == hostname {
+			return &host.Auth
+		}
+	}
+
+	return nil
+}
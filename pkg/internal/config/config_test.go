@@ -0,0 +1,55 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/kudobuilder/kitt/pkg/internal/apis/operator"
+)
+
+func TestFromFile(t *testing.T) {
+	fs := afero.NewMemMapFs()
+
+	assert.NoError(t, afero.WriteFile(fs, "/config.yaml", []byte(`
+hosts:
+  - host: artifactory.example.org
+    username: kitt
+    password:
+      env: ARTIFACTORY_PASSWORD
+  - host: localhost:8080
+    bearerToken:
+      file: /token
+    headers:
+      - name: Accept
+        value: application/octet-stream
+`), 0644))
+
+	config, err := FromFile(fs, "/config.yaml")
+	assert.NoError(t, err)
+
+	artifactory := &operator.URLAuth{
+		Username: "kitt",
+		Password: &operator.Credential{Env: "ARTIFACTORY_PASSWORD"},
+		Headers:  []operator.Header{},
+	}
+
+	local := &operator.URLAuth{
+		BearerToken: &operator.Credential{File: "/token"},
+		Headers:     []operator.Header{{Name: "Accept", Value: "application/octet-stream"}},
+	}
+
+	assert.Equal(t, artifactory, config.Auth("artifactory.example.org:443", "artifactory.example.org"))
+	assert.Equal(t, local, config.Auth("localhost:8080", "localhost"))
+	assert.Nil(t, config.Auth("localhost:9090", "localhost"))
+
+	assert.NoError(t, afero.WriteFile(fs, "/invalid.yaml", []byte(`
+hosts:
+  - host: example.org
+    token: secret
+`), 0644))
+
+	_, err = FromFile(fs, "/invalid.yaml")
+	assert.Error(t, err)
+}
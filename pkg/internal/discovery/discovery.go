@@ -0,0 +1,223 @@
+package discovery
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+
+	"github.com/Masterminds/semver/v3"
+	log "github.com/sirupsen/logrus"
+
+	o "github.com/kudobuilder/kitt/pkg/internal/apis/operator"
+	"github.com/kudobuilder/kitt/pkg/internal/resolver"
+)
+
+// Names of the groups of tag patterns capturing versions.
+const (
+	groupOperatorVersion = "operatorVersion"
+	groupAppVersion      = "appVersion"
+)
+
+// Discover replaces the version templates of 'operator', the versions with a
+// Git tag pattern, by a version for each matching tag of their Git source.
+// Versions with an operator version lower than 'since' are left out, unless
+// 'since' is nil. Versions which are also referenced explicitly are left out
+// as well. Repositories are accessed through 'cache'.
+func Discover(
+	ctx context.Context,
+	operator o.Operator,
+	since *semver.Version,
+	cache *resolver.Cache,
+) (o.Operator, error) {
+	referenced := map[string]bool{}
+
+	for _, version := range operator.Versions {
+		if !isTemplate(version) {
+			referenced[version.Version()] = true
+		}
+	}
+
+	versions := make([]o.Version, 0, len(operator.Versions))
+
+	for _, version := range operator.Versions {
+		if !isTemplate(version) {
+			versions = append(versions, version)
+			continue
+		}
+
+		tags, err := resolver.GitTags(ctx, operator, version.Git.Source, cache)
+		if err != nil {
+			return o.Operator{}, fmt.Errorf("failed to list tags of git source %q: %v", version.Git.Source, err)
+		}
+
+		fromPackage := func(version o.Version) (o.Version, error) {
+			return packageVersion(ctx, operator, version, cache)
+		}
+
+		discovered, err := expand(version, tags, since, fromPackage)
+		if err != nil {
+			return o.Operator{}, err
+		}
+
+		for _, d := range discovered {
+			if referenced[d.Version()] {
+				log.WithField("operator", operator.Name).
+					WithField("version", d.Version()).
+					WithField("tag", d.Git.Tag).
+					Debug("Skipping discovered version, it's already referenced")
+
+				continue
+			}
+
+			referenced[d.Version()] = true
+
+			versions = append(versions, d)
+		}
+	}
+
+	operator.Versions = versions
+
+	return operator, nil
+}
+
+func isTemplate(version o.Version) bool {
+	return version.Git != nil && version.Git.TagPattern != ""
+}
+
+// expand creates a version from 'template' for every tag matching its tag
+// pattern, ordered by operator version. If neither the pattern nor the
+// template provide an operator version, 'fromPackage' completes a version from
+// its operator package.
+func expand(
+	template o.Version,
+	tags []string,
+	since *semver.Version,
+	fromPackage func(o.Version) (o.Version, error),
+) ([]o.Version, error) {
+	pattern, err := regexp.Compile("^(?:" + template.Git.TagPattern + ")$")
+	if err != nil {
+		return nil, fmt.Errorf("invalid tag pattern %q: %v", template.Git.TagPattern, err)
+	}
+
+	for _, name := range pattern.SubexpNames() {
+		if name != "" && name != groupOperatorVersion && name != groupAppVersion {
+			return nil, fmt.Errorf("unknown group %q in tag pattern %q, only %q and %q are supported",
+				name, template.Git.TagPattern, groupOperatorVersion, groupAppVersion)
+		}
+	}
+
+	excluded := map[string]bool{}
+	for _, tag := range template.Git.Exclude {
+		excluded[tag] = true
+	}
+
+	type discovered struct {
+		version         o.Version
+		operatorVersion *semver.Version
+	}
+
+	result := []discovered{}
+
+	for _, tag := range tags {
+		match := pattern.FindStringSubmatch(tag)
+		if match == nil {
+			continue
+		}
+
+		logger := log.WithField("tag", tag)
+
+		if excluded[tag] {
+			logger.Debug("Skipping excluded tag")
+			continue
+		}
+
+		version := fromTag(template, tag, pattern.SubexpNames(), match)
+
+		if version.OperatorVersion == "" {
+			version, err = fromPackage(version)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		operatorVersion, err := semver.NewVersion(version.OperatorVersion)
+		if err != nil {
+			logger.WithField("operatorVersion", version.OperatorVersion).
+				Warn("Skipping tag, its operator version isn't semver")
+
+			continue
+		}
+
+		if since != nil && operatorVersion.LessThan(since) {
+			logger.Debug("Skipping tag of an operator version before the cutoff")
+			continue
+		}
+
+		result = append(result, discovered{version: version, operatorVersion: operatorVersion})
+	}
+
+	sort.SliceStable(result, func(i, j int) bool {
+		return result[i].operatorVersion.LessThan(result[j].operatorVersion)
+	})
+
+	versions := make([]o.Version, len(result))
+	for i := range result {
+		versions[i] = result[i].version
+	}
+
+	return versions, nil
+}
+
+// fromTag creates a version of a tag from a template and the groups of the
+// tag pattern matching the tag.
+func fromTag(template o.Version, tag string, names, match []string) o.Version {
+	git := *template.Git
+	git.Tag = tag
+	git.TagPattern = ""
+	git.Exclude = nil
+
+	version := template
+	version.Git = &git
+
+	for i, name := range names {
+		switch name {
+		case groupOperatorVersion:
+			version.OperatorVersion = match[i]
+		case groupAppVersion:
+			version.AppVersion = match[i]
+		}
+	}
+
+	return version
+}
+
+// packageVersion completes a version with the versions of its operator
+// package.
+func packageVersion(
+	ctx context.Context,
+	operator o.Operator,
+	version o.Version,
+	cache *resolver.Cache,
+) (o.Version, error) {
+	log.WithField("operator", operator.Name).
+		WithField("tag", version.Git.Tag).
+		Info("Reading version of discovered tag from operator package")
+
+	pkg, remover, err := resolver.Package(ctx, operator, version, cache)
+	if err != nil {
+		return o.Version{}, fmt.Errorf("failed to read version of tag %q: %v", version.Git.Tag, err)
+	}
+
+	if err := remover(); err != nil {
+		return o.Version{}, err
+	}
+
+	version.OperatorVersion = pkg.OperatorVersion.Original()
+
+	if version.AppVersion == "" && pkg.AppVersion != nil {
+		version.AppVersion = pkg.AppVersion.Original()
+	}
+
+	return version, nil
+}
@@ -0,0 +1,130 @@
+package discovery
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/Masterminds/semver/v3"
+	"github.com/stretchr/testify/assert"
+
+	o "github.com/kudobuilder/kitt/pkg/internal/apis/operator"
+)
+
+func TestExpand(t *testing.T) {
+	tags := []string{"v0.9.0", "v1.10.0", "v1.2.0", "v1.2.0-bad", "v1.3.0", "latest", "release-1.x"}
+
+	fromPackage := func(version o.Version) (o.Version, error) {
+		version.OperatorVersion = version.Git.Tag[1:]
+		version.AppVersion = "2.0.0"
+
+		return version, nil
+	}
+
+	since := semver.MustParse("1.0.0")
+
+	tests := []struct {
+		name     string
+		template o.Git
+		since    *semver.Version
+		expected []o.Version
+		err      string
+	}{
+		{
+			name:     "operator version captured",
+			template: o.Git{Source: "src", Directory: "operator", TagPattern: `v(?P<operatorVersion>\d+\.\d+\.\d+)`},
+			expected: []o.Version{
+				version("0.9.0", "", "v0.9.0"),
+				version("1.2.0", "", "v1.2.0"),
+				version("1.3.0", "", "v1.3.0"),
+				version("1.10.0", "", "v1.10.0"),
+			},
+		},
+		{
+			name: "app version captured",
+			template: o.Git{
+				Source:     "src",
+				Directory:  "operator",
+				TagPattern: `v(?P<operatorVersion>\d+\.\d+\.\d+)(-(?P<appVersion>.+))?`,
+			},
+			expected: []o.Version{
+				version("0.9.0", "", "v0.9.0"),
+				version("1.2.0", "", "v1.2.0"),
+				version("1.2.0", "bad", "v1.2.0-bad"),
+				version("1.3.0", "", "v1.3.0"),
+				version("1.10.0", "", "v1.10.0"),
+			},
+		},
+		{
+			name: "since and exclude",
+			template: o.Git{
+				Source:     "src",
+				Directory:  "operator",
+				TagPattern: `v(?P<operatorVersion>\d+\.\d+\.\d+)`,
+				Exclude:    []string{"v1.3.0"},
+			},
+			since: since,
+			expected: []o.Version{
+				version("1.2.0", "", "v1.2.0"),
+				version("1.10.0", "", "v1.10.0"),
+			},
+		},
+		{
+			name:     "versions from package",
+			template: o.Git{Source: "src", Directory: "operator", TagPattern: `v1\.\d+\.0`},
+			since:    since,
+			expected: []o.Version{
+				version("1.2.0", "2.0.0", "v1.2.0"),
+				version("1.3.0", "2.0.0", "v1.3.0"),
+				version("1.10.0", "2.0.0", "v1.10.0"),
+			},
+		},
+		{
+			name:     "no matches",
+			template: o.Git{Source: "src", Directory: "operator", TagPattern: `v2\..*`},
+			expected: []o.Version{},
+		},
+		{
+			name:     "invalid pattern",
+			template: o.Git{Source: "src", Directory: "operator", TagPattern: `v(`},
+			err:      "invalid tag pattern \"v(\": error parsing regexp: missing closing ): `^(?:v()$`",
+		},
+		{
+			name:     "unknown group",
+			template: o.Git{Source: "src", Directory: "operator", TagPattern: `v(?P<version>.+)`},
+			err: `unknown group "version" in tag pattern "v(?P<version>.+)", ` +
+				`only "operatorVersion" and "appVersion" are supported`,
+		},
+	}
+
+	for _, test := range tests {
+		template := test.template
+
+		versions, err := expand(o.Version{Git: &template}, tags, test.since, fromPackage)
+		if test.err != "" {
+			assert.EqualError(t, err, test.err, test.name)
+			continue
+		}
+
+		assert.NoError(t, err, test.name)
+		assert.Equal(t, test.expected, versions, test.name)
+	}
+}
+
+func TestExpandPackageError(t *testing.T) {
+	fromPackage := func(version o.Version) (o.Version, error) {
+		return o.Version{}, errors.New("no operator package")
+	}
+
+	template := o.Version{Git: &o.Git{Source: "src", TagPattern: "v.*"}}
+
+	_, err := expand(template, []string{"v1"}, nil, fromPackage)
+	assert.EqualError(t, err, "no operator package")
+}
+
+func version(operatorVersion, appVersion, tag string) o.Version {
+	return o.Version{
+		OperatorVersion: operatorVersion,
+		AppVersion:      appVersion,
+		Git:             &o.Git{Source: "src", Directory: "operator", Tag: tag},
+	}
+}
@@ -0,0 +1,435 @@
+package oci
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Credentials authenticate requests to a registry.
+type Credentials struct {
+	Username string
+	Password string
+}
+
+// CredentialsFunc returns the credentials of 'registry', its host and
+// optionally its port. It returns nil for registries accessed anonymously.
+type CredentialsFunc func(registry string) (*Credentials, error)
+
+// Client is a minimal client of the OCI distribution API, supporting token
+// and basic authentication.
+type Client struct {
+	client      *http.Client
+	credentials CredentialsFunc
+
+	mu sync.Mutex
+
+	// authorizations are "Authorization" headers by registry and repository.
+	authorizations map[string]string
+}
+
+// NewClient creates a new OCI registry client sending requests with 'client'.
+// If 'credentials' isn't nil, it provides the credentials of registries.
+// Otherwise, registries are accessed anonymously.
+func NewClient(client *http.Client, credentials CredentialsFunc) *Client {
+	return &Client{
+		client:         client,
+		credentials:    credentials,
+		authorizations: map[string]string{},
+	}
+}
+
+// Manifest downloads the manifest of a reference.
+func (c *Client) Manifest(ctx context.Context, ref Reference) (Manifest, Descriptor, error) {
+	resp, err := c.do(ctx, ref, http.MethodGet, c.manifestURL(ref, ref.Revision()), http.Header{
+		"Accept": []string{MediaTypeManifest},
+	}, nil)
+	if err != nil {
+		return Manifest{}, Descriptor{}, err
+	}
+
+	content, err := readResponse(resp, http.StatusOK)
+	if err != nil {
+		return Manifest{}, Descriptor{}, fmt.Errorf("failed to get manifest %q: %v", ref, err)
+	}
+
+	if ref.Digest != "" && Digest(content) != ref.Digest {
+		return Manifest{}, Descriptor{}, fmt.Errorf("manifest %q has digest %q", ref, Digest(content))
+	}
+
+	manifest := Manifest{}
+
+	if err := json.Unmarshal(content, &manifest); err != nil {
+		return Manifest{}, Descriptor{}, fmt.Errorf("failed to decode manifest %q: %v", ref, err)
+	}
+
+	return manifest, NewDescriptor(MediaTypeManifest, content), nil
+}
+
+// ManifestExists checks if a manifest exists and returns its descriptor.
+func (c *Client) ManifestExists(ctx context.Context, ref Reference) (Descriptor, bool, error) {
+	resp, err := c.do(ctx, ref, http.MethodHead, c.manifestURL(ref, ref.Revision()), http.Header{
+		"Accept": []string{MediaTypeManifest},
+	}, nil)
+	if err != nil {
+		return Descriptor{}, false, err
+	}
+
+	resp.Body.Close() //nolint:errcheck,gosec
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return Descriptor{
+			MediaType: resp.Header.Get("Content-Type"),
+			Digest:    resp.Header.Get("Docker-Content-Digest"),
+			Size:      resp.ContentLength,
+		}, true, nil
+	case http.StatusNotFound:
+		return Descriptor{}, false, nil
+	default:
+		return Descriptor{}, false, fmt.Errorf("failed to check manifest %q: %s", ref, resp.Status)
+	}
+}
+
+// Blob downloads a blob of the repository of a reference and verifies its
+// digest.
+func (c *Client) Blob(ctx context.Context, ref Reference, desc Descriptor) ([]byte, error) {
+	resp, err := c.do(ctx, ref, http.MethodGet, c.blobURL(ref, desc.Digest), nil, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	content, err := readResponse(resp, http.StatusOK)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get blob %q of %q: %v", desc.Digest, ref, err)
+	}
+
+	if Digest(content) != desc.Digest {
+		return nil, fmt.Errorf("blob %q of %q has digest %q", desc.Digest, ref, Digest(content))
+	}
+
+	return content, nil
+}
+
+// PushBlob uploads a blob to the repository of a reference, unless the
+// repository already contains it.
+func (c *Client) PushBlob(ctx context.Context, ref Reference, mediaType string, content []byte) (Descriptor, error) {
+	desc := NewDescriptor(mediaType, content)
+
+	resp, err := c.do(ctx, ref, http.MethodHead, c.blobURL(ref, desc.Digest), nil, nil)
+	if err != nil {
+		return Descriptor{}, err
+	}
+
+	resp.Body.Close() //nolint:errcheck,gosec
+
+	if resp.StatusCode == http.StatusOK {
+		return desc, nil
+	}
+
+	resp, err = c.do(ctx, ref, http.MethodPost, c.url(ref, "/blobs/uploads/"), nil, nil)
+	if err != nil {
+		return Descriptor{}, err
+	}
+
+	if _, err := readResponse(resp, http.StatusAccepted); err != nil {
+		return Descriptor{}, fmt.Errorf("failed to start upload to %q: %v", ref, err)
+	}
+
+	location, err := resp.Request.URL.Parse(resp.Header.Get("Location"))
+	if err != nil {
+		return Descriptor{}, fmt.Errorf("invalid upload location for %q: %v", ref, err)
+	}
+
+	query := location.Query()
+	query.Set("digest", desc.Digest)
+	location.RawQuery = query.Encode()
+
+	resp, err = c.do(ctx, ref, http.MethodPut, location.String(), http.Header{
+		"Content-Type": []string{"application/octet-stream"},
+	}, content)
+	if err != nil {
+		return Descriptor{}, err
+	}
+
+	if _, err := readResponse(resp, http.StatusCreated); err != nil {
+		return Descriptor{}, fmt.Errorf("failed to upload blob %q to %q: %v", desc.Digest, ref, err)
+	}
+
+	return desc, nil
+}
+
+// PushManifest uploads a manifest and tags it with the tag of the reference.
+func (c *Client) PushManifest(ctx context.Context, ref Reference, manifest Manifest) (Descriptor, error) {
+	content, err := json.Marshal(manifest)
+	if err != nil {
+		return Descriptor{}, err
+	}
+
+	resp, err := c.do(ctx, ref, http.MethodPut, c.manifestURL(ref, ref.Revision()), http.Header{
+		"Content-Type": []string{MediaTypeManifest},
+	}, content)
+	if err != nil {
+		return Descriptor{}, err
+	}
+
+	if _, err := readResponse(resp, http.StatusCreated); err != nil {
+		return Descriptor{}, fmt.Errorf("failed to upload manifest %q: %v", ref, err)
+	}
+
+	return NewDescriptor(MediaTypeManifest, content), nil
+}
+
+// do sends a request to the registry. If the registry requires
+// authentication, the request is authorized and sent again.
+func (c *Client) do(
+	ctx context.Context,
+	ref Reference,
+	method string,
+	target string,
+	header http.Header,
+	body []byte,
+) (*http.Response, error) {
+	authorizationKey := ref.Registry + "/" + ref.Repository
+
+	send := func() (*http.Response, error) {
+		req, err := http.NewRequestWithContext(ctx, method, target, bytes.NewReader(body))
+		if err != nil {
+			return nil, err
+		}
+
+		for key, values := range header {
+			req.Header[key] = values
+		}
+
+		c.mu.Lock()
+		authorization := c.authorizations[authorizationKey]
+		c.mu.Unlock()
+
+		if authorization != "" {
+			req.Header.Set("Authorization", authorization)
+		}
+
+		return c.client.Do(req)
+	}
+
+	resp, err := send()
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode != http.StatusUnauthorized {
+		return resp, nil
+	}
+
+	resp.Body.Close() //nolint:errcheck,gosec
+
+	authorization, err := c.authorize(ctx, ref.Registry, resp.Header.Get("WWW-Authenticate"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to authenticate to %q: %v", ref.Registry, err)
+	}
+
+	c.mu.Lock()
+	c.authorizations[authorizationKey] = authorization
+	c.mu.Unlock()
+
+	return send()
+}
+
+// authorize returns the "Authorization" header answering a "WWW-Authenticate"
+// challenge of 'registry'. Basic challenges are answered with the credentials
+// of the registry. For bearer challenges, a token is requested, authenticated
+// with the credentials of the registry if there are any.
+func (c *Client) authorize(ctx context.Context, registry, challenge string) (string, error) {
+	var credentials *Credentials
+
+	if c.credentials != nil {
+		var err error
+
+		credentials, err = c.credentials(registry)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	const basic = "basic"
+
+	if len(challenge) >= len(basic) && strings.EqualFold(challenge[:len(basic)], basic) {
+		if credentials == nil {
+			return "", errors.New("registry requires credentials")
+		}
+
+		auth := credentials.Username + ":" + credentials.Password
+
+		return "Basic " + base64.StdEncoding.EncodeToString([]byte(auth)), nil
+	}
+
+	token, err := c.token(ctx, challenge, credentials)
+	if err != nil {
+		return "", err
+	}
+
+	return "Bearer " + token, nil
+}
+
+// token requests a bearer token as described by a "WWW-Authenticate"
+// challenge. The token is requested anonymously if 'credentials' is nil.
+func (c *Client) token(ctx context.Context, challenge string, credentials *Credentials) (string, error) {
+	params, ok := parseChallenge(challenge)
+	if !ok {
+		return "", fmt.Errorf("unsupported authentication challenge %q", challenge)
+	}
+
+	realm, err := url.Parse(params["realm"])
+	if err != nil || params["realm"] == "" {
+		return "", fmt.Errorf("invalid authentication realm %q", params["realm"])
+	}
+
+	query := realm.Query()
+
+	for _, key := range []string{"service", "scope"} {
+		if params[key] != "" {
+			query.Set(key, params[key])
+		}
+	}
+
+	realm.RawQuery = query.Encode()
+
+	log.WithField("realm", realm.String()).
+		Debug("Requesting registry token")
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, realm.String(), nil)
+	if err != nil {
+		return "", err
+	}
+
+	if credentials != nil {
+		req.SetBasicAuth(credentials.Username, credentials.Password)
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return "", err
+	}
+
+	content, err := readResponse(resp, http.StatusOK)
+	if err != nil {
+		return "", err
+	}
+
+	response := struct {
+		Token       string `json:"token"`
+		AccessToken string `json:"access_token"`
+	}{}
+
+	if err := json.Unmarshal(content, &response); err != nil {
+		return "", fmt.Errorf("failed to decode token: %v", err)
+	}
+
+	if response.Token != "" {
+		return response.Token, nil
+	}
+
+	return response.AccessToken, nil
+}
+
+// parseChallenge parses the parameters of a bearer "WWW-Authenticate" header.
+func parseChallenge(challenge string) (map[string]string, bool) {
+	const prefix = "bearer "
+
+	if len(challenge) < len(prefix) || !strings.EqualFold(challenge[:len(prefix)], prefix) {
+		return nil, false
+	}
+
+	params := map[string]string{}
+
+	rest := challenge[len(prefix):]
+
+	for rest != "" {
+		eq := strings.Index(rest, "=")
+		if eq < 0 {
+			return nil, false
+		}
+
+		key := strings.ToLower(strings.TrimSpace(rest[:eq]))
+		rest = rest[eq+1:]
+
+		var value string
+
+		if strings.HasPrefix(rest, `"`) {
+			end := strings.Index(rest[1:], `"`)
+			if end < 0 {
+				return nil, false
+			}
+
+			value = rest[1 : end+1]
+			rest = rest[end+2:]
+		} else {
+			end := strings.Index(rest, ",")
+			if end < 0 {
+				end = len(rest)
+			}
+
+			value = rest[:end]
+			rest = rest[end:]
+		}
+
+		params[key] = value
+
+		rest = strings.TrimLeft(rest, ", ")
+	}
+
+	return params, true
+}
+
+func (c *Client) url(ref Reference, path string) string {
+	scheme := "https"
+
+	// Local registries, e.g. for testing, usually don't use TLS.
+	host := ref.Registry
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+
+	if host == "localhost" || net.ParseIP(host).IsLoopback() {
+		scheme = "http"
+	}
+
+	return fmt.Sprintf("%s://%s/v2/%s%s", scheme, ref.Registry, ref.Repository, path)
+}
+
+func (c *Client) manifestURL(ref Reference, revision string) string {
+	return c.url(ref, "/manifests/"+revision)
+}
+
+func (c *Client) blobURL(ref Reference, digest string) string {
+	return c.url(ref, "/blobs/"+digest)
+}
+
+// readResponse reads and closes the body of a response, returning an error
+// if the response doesn't have the expected status code.
+func readResponse(resp *http.Response, status int) ([]byte, error) {
+	defer resp.Body.Close() //nolint:errcheck
+
+	content, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode != status {
+		return nil, fmt.Errorf("unexpected status %q: %s", resp.Status, strings.TrimSpace(string(content)))
+	}
+
+	return content, nil
+}
@@ -0,0 +1,128 @@
+package oci_test
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/kudobuilder/kitt/pkg/internal/oci"
+	"github.com/kudobuilder/kitt/pkg/internal/oci/ocitest"
+)
+
+func TestClientPushPull(t *testing.T) {
+	registry := ocitest.NewRegistry()
+	registry.Token = "secret"
+
+	defer registry.Close()
+
+	ctx := context.Background()
+	client := oci.NewClient(http.DefaultClient, nil)
+
+	ref, err := oci.ParseReference("oci://" + registry.Host() + "/kudo/foo:1.0.0")
+	assert.NoError(t, err)
+
+	_, exists, err := client.ManifestExists(ctx, ref)
+	assert.NoError(t, err)
+	assert.False(t, exists)
+
+	config, err := client.PushBlob(ctx, ref, oci.MediaTypeConfig, []byte("{}"))
+	assert.NoError(t, err)
+
+	layer, err := client.PushBlob(ctx, ref, oci.MediaTypePackage, []byte("tarball"))
+	assert.NoError(t, err)
+
+	// Existing blobs aren't uploaded again.
+	_, err = client.PushBlob(ctx, ref, oci.MediaTypePackage, []byte("tarball"))
+	assert.NoError(t, err)
+	assert.Equal(t, 2, registry.Uploads())
+
+	pushed, err := client.PushManifest(ctx, ref, oci.Manifest{
+		SchemaVersion: 2,
+		MediaType:     oci.MediaTypeManifest,
+		Config:        config,
+		Layers:        []oci.Descriptor{layer},
+		Annotations:   map[string]string{"foo": "bar"},
+	})
+	assert.NoError(t, err)
+
+	existing, exists, err := client.ManifestExists(ctx, ref)
+	assert.NoError(t, err)
+	assert.True(t, exists)
+	assert.Equal(t, pushed.Digest, existing.Digest)
+
+	// Pull by digest.
+	ref.Tag = ""
+	ref.Digest = pushed.Digest
+
+	manifest, _, err := client.Manifest(ctx, ref)
+	assert.NoError(t, err)
+	assert.Equal(t, "bar", manifest.Annotations["foo"])
+
+	packageLayer, err := manifest.PackageLayer()
+	assert.NoError(t, err)
+
+	content, err := client.Blob(ctx, ref, packageLayer)
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("tarball"), content)
+}
+
+func TestClientCredentials(t *testing.T) {
+	credentials := func(registry string) (*oci.Credentials, error) {
+		return &oci.Credentials{Username: "kitt", Password: "secret"}, nil
+	}
+
+	tests := []struct {
+		name        string
+		token       string
+		credentials oci.CredentialsFunc
+		expectErr   bool
+	}{
+		{
+			name:        "token",
+			token:       "token",
+			credentials: credentials,
+		},
+		{
+			name:        "basic",
+			credentials: credentials,
+		},
+		{
+			name:      "anonymous token",
+			token:     "token",
+			expectErr: true,
+		},
+		{
+			name:      "anonymous basic",
+			expectErr: true,
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+
+		t.Run(test.name, func(t *testing.T) {
+			registry := ocitest.NewRegistry()
+			registry.Token = test.token
+			registry.Username = "kitt"
+			registry.Password = "secret"
+
+			defer registry.Close()
+
+			client := oci.NewClient(http.DefaultClient, test.credentials)
+
+			ref, err := oci.ParseReference("oci://" + registry.Host() + "/kudo/foo:1.0.0")
+			assert.NoError(t, err)
+
+			_, err = client.PushBlob(context.Background(), ref, oci.MediaTypeConfig, []byte("{}"))
+			if test.expectErr {
+				assert.Error(t, err)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, 1, registry.Uploads())
+		})
+	}
+}
@@ -0,0 +1,103 @@
+package oci
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/afero"
+)
+
+// dockerConfig is the part of a Docker config file storing credentials.
+type dockerConfig struct {
+	Auths map[string]dockerAuth `json:"auths"`
+}
+
+type dockerAuth struct {
+	Auth     string `json:"auth"`
+	Username string `json:"username"`
+	Password string `json:"password"`
+}
+
+// DockerConfigPath returns the path of the Docker config file, in the directory
+// set by the "DOCKER_CONFIG" environment variable or in "~/.docker". It returns
+// an empty path if neither is known.
+func DockerConfigPath() string {
+	if dir := os.Getenv("DOCKER_CONFIG"); dir != "" {
+		return filepath.Join(dir, "config.json")
+	}
+
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+
+	return filepath.Join(home, ".docker", "config.json")
+}
+
+// DockerCredentials reads the credentials of registries stored in the Docker
+// config file at 'path', e.g. by "docker login". It returns no credentials if
+// the file doesn't exist. Credentials of credential helpers aren't supported.
+func DockerCredentials(fs afero.Fs, path string) (map[string]Credentials, error) {
+	credentials := map[string]Credentials{}
+
+	content, err := afero.ReadFile(fs, path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return credentials, nil
+		}
+
+		return nil, err
+	}
+
+	config := dockerConfig{}
+
+	if err := json.Unmarshal(content, &config); err != nil {
+		return nil, fmt.Errorf("could not decode Docker config file %q: %v", path, err)
+	}
+
+	for server, auth := range config.Auths {
+		username, password := auth.Username, auth.Password
+
+		if auth.Auth != "" {
+			decoded, err := base64.StdEncoding.DecodeString(auth.Auth)
+			if err != nil {
+				return nil, fmt.Errorf("invalid auth of %q in Docker config file %q: %v", server, path, err)
+			}
+
+			colon := strings.Index(string(decoded), ":")
+			if colon < 0 {
+				return nil, fmt.Errorf("invalid auth of %q in Docker config file %q: missing password", server, path)
+			}
+
+			username, password = string(decoded[:colon]), string(decoded[colon+1:])
+		}
+
+		if username == "" {
+			continue
+		}
+
+		credentials[dockerRegistry(server)] = Credentials{Username: username, Password: password}
+	}
+
+	return credentials, nil
+}
+
+// dockerRegistry returns the registry of a server in a Docker config file,
+// which may be stored as a URL, e.g. "https://index.docker.io/v1/".
+func dockerRegistry(server string) string {
+	registry := server
+
+	for _, scheme := range []string{"https://", "http://"} {
+		registry = strings.TrimPrefix(registry, scheme)
+	}
+
+	if slash := strings.Index(registry, "/"); slash >= 0 {
+		registry = registry[:slash]
+	}
+
+	return registry
+}
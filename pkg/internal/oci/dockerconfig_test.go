@@ -0,0 +1,38 @@
+package oci
+
+import (
+	"testing"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDockerCredentials(t *testing.T) {
+	fs := afero.NewMemMapFs()
+
+	credentials, err := DockerCredentials(fs, "/config.json")
+	assert.NoError(t, err)
+	assert.Empty(t, credentials)
+
+	// "a2l0dDpzZWNyZXQ6Zm9v" is "kitt:secret:foo".
+	assert.NoError(t, afero.WriteFile(fs, "/config.json", []byte(`{
+  "auths": {
+    "https://index.docker.io/v1/": {"auth": "a2l0dDpzZWNyZXQ6Zm9v"},
+    "registry.example.org:5000": {"username": "kitt", "password": "secret"},
+    "helper.example.org": {}
+  },
+  "credsStore": "desktop"
+}`), 0600))
+
+	credentials, err = DockerCredentials(fs, "/config.json")
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]Credentials{
+		"index.docker.io":           {Username: "kitt", Password: "secret:foo"},
+		"registry.example.org:5000": {Username: "kitt", Password: "secret"},
+	}, credentials)
+
+	assert.NoError(t, afero.WriteFile(fs, "/config.json", []byte(`{"auths": {"example.org": {"auth": "kitt"}}}`), 0600))
+
+	_, err = DockerCredentials(fs, "/config.json")
+	assert.Error(t, err)
+}
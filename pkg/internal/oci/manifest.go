@@ -0,0 +1,75 @@
+package oci
+
+import (
+	"crypto/sha256"
+	"fmt"
+)
+
+// Media types of OCI manifests and operator package artifacts.
+const (
+	MediaTypeManifest = "application/vnd.oci.image.manifest.v1+json"
+	MediaTypeConfig   = "application/vnd.kudo.operator.config.v1+json"
+	MediaTypePackage  = "application/vnd.kudo.operator.package.v1.tar+gzip"
+
+	// MediaTypeLayer is the generic media type of gzipped tar layers, which
+	// is accepted for operator packages pushed by other tools.
+	MediaTypeLayer = "application/vnd.oci.image.layer.v1.tar+gzip"
+)
+
+// Annotations of operator package artifacts.
+const (
+	AnnotationOperatorName    = "dev.kudo.operator.name"
+	AnnotationOperatorVersion = "dev.kudo.operator.operatorVersion"
+	AnnotationAppVersion      = "dev.kudo.operator.appVersion"
+
+	// AnnotationTitle is the file name of a layer.
+	AnnotationTitle = "org.opencontainers.image.title"
+)
+
+// Manifest is an OCI image manifest.
+type Manifest struct {
+	SchemaVersion int               `json:"schemaVersion"`
+	MediaType     string            `json:"mediaType,omitempty"`
+	Config        Descriptor        `json:"config"`
+	Layers        []Descriptor      `json:"layers"`
+	Annotations   map[string]string `json:"annotations,omitempty"`
+}
+
+// Descriptor references content in an OCI registry.
+type Descriptor struct {
+	MediaType   string            `json:"mediaType"`
+	Digest      string            `json:"digest"`
+	Size        int64             `json:"size"`
+	Annotations map[string]string `json:"annotations,omitempty"`
+}
+
+// NewDescriptor creates a descriptor of 'content'.
+func NewDescriptor(mediaType string, content []byte) Descriptor {
+	return Descriptor{
+		MediaType: mediaType,
+		Digest:    Digest(content),
+		Size:      int64(len(content)),
+	}
+}
+
+// Digest returns the SHA-256 digest of 'content' in OCI format.
+func Digest(content []byte) string {
+	return fmt.Sprintf("sha256:%x", sha256.Sum256(content))
+}
+
+// PackageLayer returns the layer of a manifest containing the operator
+// package tarball.
+func (m Manifest) PackageLayer() (Descriptor, error) {
+	for _, layer := range m.Layers {
+		if layer.MediaType == MediaTypePackage {
+			return layer, nil
+		}
+	}
+
+	// Artifacts pushed by other tools may use generic layer media types.
+	if len(m.Layers) == 1 && m.Layers[0].MediaType == MediaTypeLayer {
+		return m.Layers[0], nil
+	}
+
+	return Descriptor{}, fmt.Errorf("manifest has no layer of media type %q", MediaTypePackage)
+}
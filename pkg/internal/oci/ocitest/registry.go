@@ -0,0 +1,164 @@
+// Package ocitest provides an in-memory OCI registry for tests.
+package ocitest
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+
+	"github.com/kudobuilder/kitt/pkg/internal/oci"
+)
+
+// Registry is an in-memory implementation of the parts of the OCI
+// distribution API used by kitt.
+type Registry struct {
+	*httptest.Server
+
+	// Token, if set, has to be provided as a bearer token. It is handed out
+	// by the registry's token endpoint.
+	Token string
+
+	// Username and Password, if set, are required with basic authentication
+	// by the token endpoint, or by the registry itself if 'Token' isn't set.
+	Username string
+	Password string
+
+	mu        sync.Mutex
+	manifests map[string][]byte
+	blobs     map[string][]byte
+	uploads   int
+}
+
+// NewRegistry starts a new registry. Callers have to close it.
+func NewRegistry() *Registry {
+	r := &Registry{
+		manifests: map[string][]byte{},
+		blobs:     map[string][]byte{},
+	}
+
+	r.Server = httptest.NewServer(http.HandlerFunc(r.serve))
+
+	return r
+}
+
+// Host returns the host and port of the registry, to be used in references.
+func (r *Registry) Host() string {
+	return strings.TrimPrefix(r.URL, "http://")
+}
+
+// Uploads returns the number of uploaded blobs.
+func (r *Registry) Uploads() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.uploads
+}
+
+func (r *Registry) serve(w http.ResponseWriter, req *http.Request) {
+	if req.URL.Path == "/token" {
+		if !r.authenticated(req) {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		fmt.Fprintf(w, `{"token": %q}`, r.Token)
+
+		return
+	}
+
+	switch {
+	case r.Token != "" && req.Header.Get("Authorization") != "Bearer "+r.Token:
+		w.Header().Set("WWW-Authenticate",
+			fmt.Sprintf(`Bearer realm="%s/token",service="registry",scope="repository:foo:pull"`, r.URL))
+		w.WriteHeader(http.StatusUnauthorized)
+
+		return
+	case r.Token == "" && !r.authenticated(req):
+		w.Header().Set("WWW-Authenticate", `Basic realm="registry"`)
+		w.WriteHeader(http.StatusUnauthorized)
+
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	path := strings.TrimPrefix(req.URL.Path, "/v2/")
+
+	switch {
+	case strings.Contains(path, "/manifests/"):
+		i := strings.LastIndex(path, "/manifests/")
+		r.serveManifest(w, req, path[:i], path[i+len("/manifests/"):])
+	case strings.HasSuffix(path, "/blobs/uploads/") && req.Method == http.MethodPost:
+		w.Header().Set("Location", fmt.Sprintf("/v2/%supload", path))
+		w.WriteHeader(http.StatusAccepted)
+	case strings.HasSuffix(path, "/blobs/uploads/upload") && req.Method == http.MethodPut:
+		content, _ := ioutil.ReadAll(req.Body)
+
+		digest := req.URL.Query().Get("digest")
+		if oci.Digest(content) != digest {
+			http.Error(w, "digest mismatch", http.StatusBadRequest)
+			return
+		}
+
+		r.blobs[digest] = content
+		r.uploads++
+
+		w.WriteHeader(http.StatusCreated)
+	case strings.Contains(path, "/blobs/"):
+		content, ok := r.blobs[path[strings.LastIndex(path, "/")+1:]]
+		if !ok {
+			http.NotFound(w, req)
+			return
+		}
+
+		_, _ = w.Write(content)
+	default:
+		http.NotFound(w, req)
+	}
+}
+
+// authenticated checks the basic authentication of a request, if the registry
+// requires it.
+func (r *Registry) authenticated(req *http.Request) bool {
+	if r.Username == "" {
+		return true
+	}
+
+	username, password, ok := req.BasicAuth()
+
+	return ok && username == r.Username && password == r.Password
+}
+
+func (r *Registry) serveManifest(w http.ResponseWriter, req *http.Request, repository, revision string) {
+	key := repository + "@" + revision
+
+	switch req.Method {
+	case http.MethodPut:
+		content, _ := ioutil.ReadAll(req.Body)
+
+		r.manifests[key] = content
+		r.manifests[repository+"@"+oci.Digest(content)] = content
+
+		w.WriteHeader(http.StatusCreated)
+	case http.MethodGet, http.MethodHead:
+		content, ok := r.manifests[key]
+		if !ok {
+			http.NotFound(w, req)
+			return
+		}
+
+		w.Header().Set("Content-Type", oci.MediaTypeManifest)
+		w.Header().Set("Docker-Content-Digest", oci.Digest(content))
+		w.Header().Set("Content-Length", fmt.Sprint(len(content)))
+
+		if req.Method == http.MethodGet {
+			_, _ = w.Write(content)
+		}
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
@@ -0,0 +1,90 @@
+package oci
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Scheme is the optional prefix of OCI references.
+const Scheme = "oci://"
+
+// Reference identifies a manifest in an OCI registry, either by tag or by
+// digest.
+type Reference struct {
+	// Registry is the host, and optionally the port, of the registry.
+	Registry string
+
+	// Repository is the name of the repository in the registry, e.g.
+	// "kudo/operators/kafka".
+	Repository string
+
+	// Tag of the manifest. Either this or 'Digest' is set.
+	Tag string
+
+	// Digest of the manifest. Either this or 'Tag' is set.
+	Digest string
+}
+
+// ParseReference parses references like "oci://registry/namespace/name:tag"
+// or "oci://registry/namespace/name@sha256:...". The "oci://" prefix is
+// optional. References without a tag or digest use the "latest" tag.
+func ParseReference(reference string) (Reference, error) {
+	rest := strings.TrimPrefix(reference, Scheme)
+
+	slash := strings.Index(rest, "/")
+	if slash <= 0 {
+		return Reference{}, fmt.Errorf("invalid OCI reference %q: missing registry", reference)
+	}
+
+	ref := Reference{Registry: rest[:slash]}
+
+	rest = rest[slash+1:]
+
+	if at := strings.Index(rest, "@"); at >= 0 {
+		ref.Digest = rest[at+1:]
+		rest = rest[:at]
+
+		if !strings.Contains(ref.Digest, ":") {
+			return Reference{}, fmt.Errorf("invalid OCI reference %q: invalid digest %q", reference, ref.Digest)
+		}
+	} else if colon := strings.LastIndex(rest, ":"); colon >= 0 {
+		ref.Tag = rest[colon+1:]
+		rest = rest[:colon]
+	} else {
+		ref.Tag = "latest"
+	}
+
+	ref.Repository = rest
+
+	if ref.Repository == "" || ref.Repository != strings.ToLower(ref.Repository) {
+		return Reference{}, fmt.Errorf("invalid OCI reference %q: invalid repository %q", reference, ref.Repository)
+	}
+
+	if ref.Digest == "" && ref.Tag == "" {
+		return Reference{}, fmt.Errorf("invalid OCI reference %q: empty tag", reference)
+	}
+
+	return ref, nil
+}
+
+// WithTag returns a reference to a different tag in the same repository.
+func (r Reference) WithTag(tag string) Reference {
+	return Reference{Registry: r.Registry, Repository: r.Repository, Tag: tag}
+}
+
+// Revision returns the digest of the reference, or its tag if no digest is set.
+func (r Reference) Revision() string {
+	if r.Digest != "" {
+		return r.Digest
+	}
+
+	return r.Tag
+}
+
+func (r Reference) String() string {
+	if r.Digest != "" {
+		return fmt.Sprintf("%s/%s@%s", r.Registry, r.Repository, r.Digest)
+	}
+
+	return fmt.Sprintf("%s/%s:%s", r.Registry, r.Repository, r.Tag)
+}
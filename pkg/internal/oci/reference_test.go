@@ -0,0 +1,55 @@
+package oci
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseReference(t *testing.T) {
+	tests := []struct {
+		reference string
+		expected  Reference
+		expectErr bool
+	}{
+		{
+			reference: "oci://registry.example.org/kudo/kafka:1.0.0",
+			expected:  Reference{Registry: "registry.example.org", Repository: "kudo/kafka", Tag: "1.0.0"},
+		},
+		{
+			reference: "localhost:5000/kafka",
+			expected:  Reference{Registry: "localhost:5000", Repository: "kafka", Tag: "latest"},
+		},
+		{
+			reference: "oci://localhost:5000/kafka@sha256:abc",
+			expected:  Reference{Registry: "localhost:5000", Repository: "kafka", Digest: "sha256:abc"},
+		},
+		{
+			reference: "oci://kafka",
+			expectErr: true,
+		},
+		{
+			reference: "oci://localhost:5000/Kafka:1.0.0",
+			expectErr: true,
+		},
+		{
+			reference: "oci://localhost:5000/kafka@abc",
+			expectErr: true,
+		},
+		{
+			reference: "oci://localhost:5000/kafka:",
+			expectErr: true,
+		},
+	}
+
+	for _, test := range tests {
+		ref, err := ParseReference(test.reference)
+
+		if test.expectErr {
+			assert.Error(t, err, test.reference)
+		} else {
+			assert.NoError(t, err, test.reference)
+			assert.Equal(t, test.expected, ref, test.reference)
+		}
+	}
+}
@@ -0,0 +1,40 @@
+package output
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"gopkg.in/yaml.v2"
+)
+
+// Formats lists the supported output formats.
+const Formats = `"text", "json" or "yaml"`
+
+// CheckFormat returns an error if 'format' isn't supported.
+func CheckFormat(format string) error {
+	switch format {
+	case "text", "json", "yaml":
+		return nil
+	default:
+		return fmt.Errorf("unknown output format %q, must be one of %s", format, Formats)
+	}
+}
+
+// Write prints 'v' in one of the formats "text", "json" or "yaml".
+// The "text" format is printed by 'text', the other formats encode 'v'.
+func Write(w io.Writer, format string, v interface{}, text func(w io.Writer) error) error {
+	switch format {
+	case "text":
+		return text(w)
+	case "json":
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+
+		return encoder.Encode(v)
+	case "yaml":
+		return yaml.NewEncoder(w).Encode(v)
+	default:
+		return CheckFormat(format)
+	}
+}
@@ -0,0 +1,98 @@
+package parallel
+
+import (
+	"context"
+	"sync"
+)
+
+// Ordered calls 'work' for every index in [0, n) using at most 'parallelism'
+// concurrent goroutines. 'done' is called sequentially for every index in
+// ascending order, once 'work' for that index has returned. Work for an index
+// only starts once fewer than 'parallelism' indices are pending, i.e. have
+// been started but not yet passed to 'done'.
+//
+// If 'done' returns an error or 'ctx' is cancelled, no further work is started
+// and the context passed to running work is cancelled. Ordered waits for all
+// running work to return before returning the error.
+func Ordered(
+	ctx context.Context,
+	parallelism int,
+	n int,
+	work func(ctx context.Context, i int),
+	done func(i int) error,
+) error {
+	if parallelism < 1 {
+		parallelism = 1
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	finished := make([]chan struct{}, n)
+	for i := range finished {
+		finished[i] = make(chan struct{})
+	}
+
+	// Indices that haven't been started because 'ctx' has been cancelled.
+	skipped := make([]bool, n)
+
+	// Each pending index holds a slot.
+	slots := make(chan struct{}, parallelism)
+
+	var wg sync.WaitGroup
+
+	wg.Add(1)
+
+	go func() {
+		defer wg.Done()
+
+		for i := 0; i < n; i++ {
+			select {
+			case slots <- struct{}{}:
+			case <-ctx.Done():
+			}
+
+			if ctx.Err() != nil {
+				for j := i; j < n; j++ {
+					skipped[j] = true
+					close(finished[j])
+				}
+
+				return
+			}
+
+			wg.Add(1)
+
+			go func(i int) {
+				defer wg.Done()
+				defer close(finished[i])
+
+				work(ctx, i)
+			}(i)
+		}
+	}()
+
+	var err error
+
+	for i := 0; i < n; i++ {
+		<-finished[i]
+
+		if skipped[i] {
+			err = ctx.Err()
+			break
+		}
+
+		err = done(i)
+
+		<-slots
+
+		if err != nil {
+			break
+		}
+	}
+
+	cancel()
+	wg.Wait()
+
+	return err
+}
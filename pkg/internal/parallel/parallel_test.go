@@ -0,0 +1,98 @@
+package parallel
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOrdered(t *testing.T) {
+	const n = 20
+
+	var mu sync.Mutex
+
+	running, maxRunning := 0, 0
+
+	results := make([]int, n)
+	order := []int{}
+
+	work := func(ctx context.Context, i int) {
+		mu.Lock()
+		running++
+		if running > maxRunning {
+			maxRunning = running
+		}
+		mu.Unlock()
+
+		// Later indices finish first.
+		time.Sleep(time.Duration(n-i) * time.Millisecond)
+
+		results[i] = i * i
+
+		mu.Lock()
+		running--
+		mu.Unlock()
+	}
+
+	done := func(i int) error {
+		assert.Equal(t, i*i, results[i])
+
+		order = append(order, i)
+
+		return nil
+	}
+
+	assert.NoError(t, Ordered(context.Background(), 4, n, work, done))
+
+	expected := make([]int, n)
+	for i := range expected {
+		expected[i] = i
+	}
+
+	assert.Equal(t, expected, order)
+	assert.LessOrEqual(t, maxRunning, 4)
+}
+
+func TestOrderedError(t *testing.T) {
+	var mu sync.Mutex
+
+	started := map[int]bool{}
+
+	work := func(ctx context.Context, i int) {
+		mu.Lock()
+		started[i] = true
+		mu.Unlock()
+	}
+
+	done := func(i int) error {
+		if i == 2 {
+			return errors.New("failed")
+		}
+
+		return nil
+	}
+
+	assert.EqualError(t, Ordered(context.Background(), 2, 100, work, done), "failed")
+
+	// At most two indices are pending when 'done' fails for index 2.
+	assert.Less(t, len(started), 6)
+}
+
+func TestOrderedCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	calls := 0
+
+	err := Ordered(ctx, 2, 10, func(ctx context.Context, i int) {}, func(i int) error {
+		calls++
+		return nil
+	})
+
+	assert.Equal(t, context.Canceled, err)
+	assert.Zero(t, calls)
+}
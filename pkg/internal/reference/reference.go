@@ -0,0 +1,99 @@
+package reference
+
+import (
+	"context"
+
+	"github.com/kudobuilder/kitt/pkg/internal/apis/operator"
+	"github.com/kudobuilder/kitt/pkg/internal/parallel"
+	"github.com/kudobuilder/kitt/pkg/internal/repo"
+	"github.com/kudobuilder/kitt/pkg/internal/resolver"
+)
+
+// Reference is a single version of an operator.
+type Reference struct {
+	Operator operator.Operator
+	Version  operator.Version
+}
+
+// List returns a reference for every version of 'operators'.
+func List(operators []operator.Operator) []Reference {
+	references := []Reference{}
+
+	for _, operator := range operators {
+		for _, version := range operator.Versions {
+			references = append(references, Reference{Operator: operator, Version: version})
+		}
+	}
+
+	return references
+}
+
+// Resolved is the result of resolving a reference. 'Err' is set if the
+// reference couldn't be resolved.
+type Resolved struct {
+	Package repo.Package
+	Err     error
+
+	remover func() error
+}
+
+// remove removes the temporary directory of a package, if it hasn't been
+// removed yet.
+func (r *Resolved) remove() error {
+	if r.remover == nil {
+		return nil
+	}
+
+	remover := r.remover
+	r.remover = nil
+
+	return remover()
+}
+
+// Resolve resolves the packages of 'references' with 'cache'. Up to
+// 'parallelism' references are resolved concurrently, 'start' is called before
+// resolving a reference, e.g. to log it. 'done' is called for every resolved
+// reference one after another in the order of 'references'. Once 'done'
+// returns, the temporary directory of the package is removed.
+//
+// If 'done' returns an error, no further references are resolved and the
+// error is returned.
+func Resolve(
+	ctx context.Context,
+	references []Reference,
+	cache *resolver.Cache,
+	parallelism int,
+	start func(Reference),
+	done func(Reference, Resolved) error,
+) (err error) {
+	resolved := make([]Resolved, len(references))
+
+	// Packages that haven't been passed to 'done' because of an earlier error,
+	// e.g. a cancelled context, still have temporary directories that need to
+	// be removed.
+	defer func() {
+		for i := range resolved {
+			if rerr := resolved[i].remove(); rerr != nil && err == nil {
+				err = rerr
+			}
+		}
+	}()
+
+	return parallel.Ordered(
+		ctx,
+		parallelism,
+		len(references),
+		func(ctx context.Context, i int) {
+			start(references[i])
+
+			resolved[i].Package, resolved[i].remover, resolved[i].Err = resolver.Package(
+				ctx, references[i].Operator, references[i].Version, cache)
+		},
+		func(i int) error {
+			if err := done(references[i], resolved[i]); err != nil {
+				return err
+			}
+
+			return resolved[i].remove()
+		})
+}
@@ -0,0 +1,241 @@
+package repo
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/kudobuilder/kudo/pkg/kudoctl/packages/writer"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/kudobuilder/kitt/pkg/internal/oci"
+)
+
+// OCIRepo manages operator packages as artifacts in an OCI registry.
+// Each operator is stored in the registry repository "<namespace>/<name>",
+// each version of the operator is a tag of this repository.
+// Like 'SyncedRepo', packages are staged by 'Add' and pushed once these
+// changes are committed by 'Commit'. Registries don't support transactions:
+// if pushing fails, artifacts that have already been pushed remain.
+type OCIRepo struct {
+	client *oci.Client
+	base   oci.Reference
+
+	// Artifacts that have been added since the last commit, in the order of
+	// their addition.
+	staged []ociArtifact
+
+	URL string
+}
+
+// ociArtifact is an operator package tarball to be pushed.
+type ociArtifact struct {
+	ref     oci.Reference
+	pkg     Package
+	tarball []byte
+}
+
+// NewOCIRepo creates a new repository for a registry namespace referenced
+// as "oci://registry/namespace".
+func NewOCIRepo(client *oci.Client, repoURL string) (*OCIRepo, error) {
+	rest := strings.Trim(strings.TrimPrefix(repoURL, oci.Scheme), "/")
+
+	registry, namespace := rest, ""
+	if slash := strings.Index(rest, "/"); slash >= 0 {
+		registry, namespace = rest[:slash], rest[slash+1:]
+	}
+
+	if registry == "" || strings.ContainsAny(namespace, ":@") {
+		return nil, fmt.Errorf("invalid OCI repository %q", repoURL)
+	}
+
+	return &OCIRepo{
+		client: client,
+		base:   oci.Reference{Registry: registry, Repository: namespace},
+		URL:    repoURL,
+	}, nil
+}
+
+// Contains checks if a specific operator package is in the registry.
+// Staged packages are considered to be in the registry.
+func (r *OCIRepo) Contains(ctx context.Context, pkg Package) (bool, error) {
+	ref := r.reference(pkg)
+
+	for _, artifact := range r.staged {
+		if artifact.ref == ref {
+			return true, nil
+		}
+	}
+
+	_, exists, err := r.client.ManifestExists(ctx, ref)
+
+	return exists, err
+}
+
+// Check creates the tarball of an operator package and determines how
+// adding it would change the registry, without changing the registry.
+func (r *OCIRepo) Check(ctx context.Context, pkg Package) (Addition, error) {
+	addition, _, err := r.prepare(ctx, pkg)
+
+	return addition, err
+}
+
+// Add stages an operator package in the registry. The package is pushed
+// once 'Commit' is called.
+// If the registry already contains a package tarball with the same digest,
+// nothing is staged.
+func (r *OCIRepo) Add(ctx context.Context, pkg Package) (Addition, error) {
+	addition, tarball, err := r.prepare(ctx, pkg)
+	if err != nil {
+		return Addition{}, err
+	}
+
+	if !addition.Changed() {
+		log.WithField("repository", r.URL).
+			WithField("tarball", addition.Tarball).
+			WithField("digest", addition.Digest).
+			Debug("Operator package is unchanged")
+
+		return addition, nil
+	}
+
+	ref := r.reference(pkg)
+
+	// A package that is added again replaces the previously staged package.
+	staged := r.staged[:0]
+
+	for _, artifact := range r.staged {
+		if artifact.ref != ref {
+			staged = append(staged, artifact)
+		}
+	}
+
+	r.staged = append(staged, ociArtifact{ref: ref, pkg: pkg, tarball: tarball})
+
+	return addition, nil
+}
+
+// prepare creates the tarball of an operator package and looks up the
+// digest of the package tarball currently in the registry.
+func (r *OCIRepo) prepare(ctx context.Context, pkg Package) (Addition, []byte, error) {
+	tarballName := fmt.Sprintf("%s.tgz", pkg.String())
+
+	var buf bytes.Buffer
+
+	// Path needs to be an empty string, otherwise wrong filenames will be created
+	if err := writer.TgzDir(pkg, "", &buf); err != nil {
+		return Addition{}, nil, fmt.Errorf("failed to tar operator package %q: %v", tarballName, err)
+	}
+
+	addition := Addition{
+		Tarball: tarballName,
+		Digest:  strings.TrimPrefix(oci.Digest(buf.Bytes()), "sha256:"),
+	}
+
+	ref := r.reference(pkg)
+
+	_, exists, err := r.client.ManifestExists(ctx, ref)
+	if err != nil {
+		return Addition{}, nil, err
+	}
+
+	if exists {
+		manifest, _, err := r.client.Manifest(ctx, ref)
+		if err != nil {
+			return Addition{}, nil, err
+		}
+
+		layer, err := manifest.PackageLayer()
+		if err != nil {
+			return Addition{}, nil, fmt.Errorf("invalid operator artifact %q: %v", ref, err)
+		}
+
+		addition.PreviousDigest = strings.TrimPrefix(layer.Digest, "sha256:")
+	}
+
+	return addition, buf.Bytes(), nil
+}
+
+// Commit pushes all staged packages to the registry.
+func (r *OCIRepo) Commit(ctx context.Context) error {
+	for len(r.staged) > 0 {
+		artifact := r.staged[0]
+
+		log.WithField("repository", r.URL).
+			WithField("reference", artifact.ref.String()).
+			Info("Pushing operator package")
+
+		if err := r.push(ctx, artifact); err != nil {
+			r.Rollback()
+
+			return fmt.Errorf("failed to push operator package %q: %v", artifact.ref, err)
+		}
+
+		r.staged = r.staged[1:]
+	}
+
+	r.staged = nil
+
+	return nil
+}
+
+// Rollback discards all staged packages.
+func (r *OCIRepo) Rollback() {
+	r.staged = nil
+}
+
+func (r *OCIRepo) push(ctx context.Context, artifact ociArtifact) error {
+	annotations := map[string]string{
+		oci.AnnotationOperatorName:    artifact.pkg.OperatorName,
+		oci.AnnotationOperatorVersion: artifact.pkg.OperatorVersion.String(),
+	}
+
+	if artifact.pkg.AppVersion != nil {
+		annotations[oci.AnnotationAppVersion] = artifact.pkg.AppVersion.String()
+	}
+
+	config, err := json.Marshal(annotations)
+	if err != nil {
+		return err
+	}
+
+	configDesc, err := r.client.PushBlob(ctx, artifact.ref, oci.MediaTypeConfig, config)
+	if err != nil {
+		return err
+	}
+
+	layerDesc, err := r.client.PushBlob(ctx, artifact.ref, oci.MediaTypePackage, artifact.tarball)
+	if err != nil {
+		return err
+	}
+
+	layerDesc.Annotations = map[string]string{
+		oci.AnnotationTitle: fmt.Sprintf("%s.tgz", artifact.pkg.String()),
+	}
+
+	_, err = r.client.PushManifest(ctx, artifact.ref, oci.Manifest{
+		SchemaVersion: 2,
+		MediaType:     oci.MediaTypeManifest,
+		Config:        configDesc,
+		Layers:        []oci.Descriptor{layerDesc},
+		Annotations:   annotations,
+	})
+
+	return err
+}
+
+// reference returns the registry reference of a package.
+// Tags are the package version, as used in tarball names. Semver build
+// metadata is separated by '_' instead of '+', which isn't allowed in tags.
+func (r *OCIRepo) reference(pkg Package) oci.Reference {
+	tag := strings.TrimPrefix(pkg.String(), pkg.OperatorName+"-")
+
+	return oci.Reference{
+		Registry:   r.base.Registry,
+		Repository: path.Join(r.base.Repository, pkg.OperatorName),
+		Tag:        strings.ReplaceAll(tag, "+", "_"),
+	}
+}
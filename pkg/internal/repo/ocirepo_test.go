@@ -0,0 +1,74 @@
+package repo
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/kudobuilder/kitt/pkg/internal/oci"
+	"github.com/kudobuilder/kitt/pkg/internal/oci/ocitest"
+)
+
+func TestOCIRepo(t *testing.T) {
+	registry := ocitest.NewRegistry()
+	defer registry.Close()
+
+	ctx := context.Background()
+	client := oci.NewClient(http.DefaultClient, nil)
+
+	repo, err := NewOCIRepo(client, "oci://"+registry.Host()+"/kudo")
+	assert.NoError(t, err)
+
+	pkg := createPackage(t, `name: foo
+operatorVersion: "1.0.0"
+appVersion: "2.0.0"
+`)
+
+	contains, err := repo.Contains(ctx, pkg)
+	assert.NoError(t, err)
+	assert.False(t, contains)
+
+	addition, err := repo.Add(ctx, pkg)
+	assert.NoError(t, err)
+	assert.False(t, addition.Replaced())
+	assert.Equal(t, "foo-2.0.0_1.0.0.tgz", addition.Tarball)
+
+	// Nothing is pushed before the commit.
+	assert.Zero(t, registry.Uploads())
+
+	contains, err = repo.Contains(ctx, pkg)
+	assert.NoError(t, err)
+	assert.True(t, contains)
+
+	assert.NoError(t, repo.Commit(ctx))
+
+	ref := oci.Reference{Registry: registry.Host(), Repository: "kudo/foo", Tag: "2.0.0_1.0.0"}
+
+	manifest, _, err := client.Manifest(ctx, ref)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{
+		oci.AnnotationOperatorName:    "foo",
+		oci.AnnotationOperatorVersion: "1.0.0",
+		oci.AnnotationAppVersion:      "2.0.0",
+	}, manifest.Annotations)
+
+	layer, err := manifest.PackageLayer()
+	assert.NoError(t, err)
+	assert.Equal(t, "sha256:"+addition.Digest, layer.Digest)
+
+	// Adding the same package again doesn't change the registry.
+	repo, err = NewOCIRepo(client, "oci://"+registry.Host()+"/kudo")
+	assert.NoError(t, err)
+
+	contains, err = repo.Contains(ctx, pkg)
+	assert.NoError(t, err)
+	assert.True(t, contains)
+
+	addition, err = repo.Add(ctx, pkg)
+	assert.NoError(t, err)
+	assert.True(t, addition.Replaced())
+	assert.False(t, addition.Changed())
+	assert.Empty(t, repo.staged)
+}
@@ -17,6 +17,10 @@ type Package struct {
 	OperatorName    string
 	OperatorVersion semver.Version
 	AppVersion      *semver.Version
+
+	// Commit is the SHA of the Git commit the package has been checked out
+	// from, if it has been resolved from a Git repository.
+	Commit string
 }
 
 // NewPackage creates a new Package by extracting version information from a
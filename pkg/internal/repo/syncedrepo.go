@@ -1,12 +1,17 @@
 package repo
 
 import (
+	"bytes"
 	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
+	"strings"
 	"time"
 
 	"github.com/Masterminds/semver/v3"
+	"github.com/kudobuilder/kudo/pkg/kudoctl/files"
+	"github.com/kudobuilder/kudo/pkg/kudoctl/packages/reader"
 	"github.com/kudobuilder/kudo/pkg/kudoctl/packages/writer"
 	kudo "github.com/kudobuilder/kudo/pkg/kudoctl/util/repo"
 	log "github.com/sirupsen/logrus"
@@ -14,17 +19,34 @@ import (
 )
 
 // SyncedRepo manages operator packages of a file system.
-// If packages are added, an updated index file is created.
+// Packages are staged by 'Add' and 'Remove' and an updated index file is
+// created once these changes are committed by 'Commit'.
+// Staged packages are written to temporary files that are moved into place
+// on commit, so that readers of the repository never see partially written
+// files.
 type SyncedRepo struct {
 	fs    afero.Fs
 	index map[string]kudo.PackageVersions
 
+	// Tarballs that have been added since the last commit, mapping tarball
+	// names to the names of their temporary files.
+	staged map[string]string
+
+	// Tarballs that have been removed since the last commit.
+	removed map[string]bool
+
+	// Index entries before packages were staged, restored by 'Rollback'.
+	committed map[string]kudo.PackageVersions
+
 	URL string
 }
 
 // NewSyncedRepo create a new repository in a file system.
 // The repoURL parameter determines the URL to use for package file URLs
 // in the repository index.
+// Temporary files left behind by an interrupted commit are removed and
+// replaced tarballs that are still indexed are restored from their backups,
+// hence a repository must not be updated concurrently.
 func NewSyncedRepo(fs afero.Fs, repoURL string) (*SyncedRepo, error) {
 	index := map[string]kudo.PackageVersions{}
 
@@ -50,6 +72,10 @@ func NewSyncedRepo(fs afero.Fs, repoURL string) (*SyncedRepo, error) {
 		index = i.Entries
 	}
 
+	if err := recoverTempFiles(fs, repoURL, index); err != nil {
+		return nil, err
+	}
+
 	return &SyncedRepo{
 		fs:    fs,
 		index: index,
@@ -57,23 +83,35 @@ func NewSyncedRepo(fs afero.Fs, repoURL string) (*SyncedRepo, error) {
 	}, nil
 }
 
+// OpenSyncedRepo opens a repository in the directory 'repoPath' of the local
+// file system.
+func OpenSyncedRepo(repoPath string, repoURL string) (*SyncedRepo, error) {
+	repoFs := afero.NewBasePathFs(afero.NewOsFs(), repoPath)
+
+	isDir, err := afero.IsDir(repoFs, "")
+	if err != nil {
+		return nil, fmt.Errorf("failed to open repository path %q: %v", repoPath, err)
+	}
+
+	if !isDir {
+		return nil, fmt.Errorf("repository path %q is not a directory", repoPath)
+	}
+
+	syncedRepo, err := NewSyncedRepo(repoFs, repoURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open repository %q: %v", repoPath, err)
+	}
+
+	return syncedRepo, nil
+}
+
 // Contains checks if a specific operator package is in the repository.
+// Staged packages are considered to be in the repository.
 func (s SyncedRepo) Contains(pkg Package) bool {
 	entries, ok := s.index[pkg.OperatorName]
 	if ok {
 		for _, entry := range entries {
-			var appVersion *semver.Version
-
-			// AppVersion is optional
-			if entry.AppVersion != "" {
-				appVersion = semver.MustParse(entry.AppVersion)
-			}
-
-			if pkg.Equal(Package{
-				OperatorName:    entry.Name,
-				OperatorVersion: *semver.MustParse(entry.OperatorVersion),
-				AppVersion:      appVersion,
-			}) {
+			if pkg.Equal(entryPackage(entry)) {
 				return true
 			}
 		}
@@ -82,49 +120,745 @@ func (s SyncedRepo) Contains(pkg Package) bool {
 	return false
 }
 
-// Add adds an operator package to the repository.
-// The package contents are provided as a file system.
-func (s *SyncedRepo) Add(pkg Package) (tarballName string, err error) {
-	tarballName = fmt.Sprintf("%s.tgz", pkg.String())
+// entryPackage creates a package containing the version information of an
+// index entry.
+func entryPackage(entry *kudo.PackageVersion) Package {
+	var appVersion *semver.Version
+
+	// AppVersion is optional
+	if entry.AppVersion != "" {
+		appVersion = semver.MustParse(entry.AppVersion)
+	}
+
+	return Package{
+		OperatorName:    entry.Name,
+		OperatorVersion: *semver.MustParse(entry.OperatorVersion),
+		AppVersion:      appVersion,
+	}
+}
+
+// Addition describes an operator package added to a repository.
+type Addition struct {
+	// Tarball is the file name of the package tarball.
+	Tarball string
+
+	// Digest is the SHA256 digest of the package tarball.
+	Digest string
+
+	// PreviousDigest is the digest of the indexed package tarball of the same
+	// version, if the repository already contained this version.
+	PreviousDigest string
+}
+
+// Replaced returns true if the repository already contained this package
+// version.
+func (a Addition) Replaced() bool {
+	return a.PreviousDigest != ""
+}
+
+// Changed returns true if the content of the package differs from the
+// content of the package previously indexed for this version, or if there
+// wasn't such a package.
+func (a Addition) Changed() bool {
+	return a.Digest != a.PreviousDigest
+}
+
+// Check creates the tarball of an operator package and determines how
+// adding it would change the repository, without changing the repository.
+func (s *SyncedRepo) Check(pkg Package) (Addition, error) {
+	addition, _, _, err := s.prepare(pkg)
+
+	return addition, err
+}
+
+// Add stages an operator package in the repository.
+// The package contents are provided as a file system. The package is written
+// as a temporary tarball, which is moved into place and indexed once 'Commit'
+// is called.
+// If the repository already contains a package tarball with the same digest,
+// nothing is staged.
+func (s *SyncedRepo) Add(pkg Package) (Addition, error) {
+	addition, entry, tarball, err := s.prepare(pkg)
+	if err != nil {
+		return Addition{}, err
+	}
+
+	tarballName := addition.Tarball
+
+	if !addition.Changed() {
+		log.WithField("repository", s.URL).
+			WithField("tarball", tarballName).
+			WithField("digest", addition.Digest).
+			Debug("Operator package is unchanged")
 
+		return addition, nil
+	}
+
+	tempName, err := writeTempFile(s.fs, tarballName, tarball)
+	if err != nil {
+		return Addition{}, fmt.Errorf("failed to create operator package %q : %v", tarballName, err)
+	}
+
+	s.begin()
+
+	// A package that is added again replaces the previously staged tarball.
+	if previous, ok := s.staged[tarballName]; ok {
+		if err := s.fs.Remove(previous); err != nil {
+			return Addition{}, fmt.Errorf("failed to remove staged operator package %q: %v", tarballName, err)
+		}
+	}
+
+	s.setEntry(entry)
+	s.staged[tarballName] = tempName
+	delete(s.removed, tarballName)
+
+	return addition, nil
+}
+
+// prepare creates the tarball and index entry of an operator package.
+// The tarball is named after the metadata of its index entry, like the
+// tarballs of all indexed packages.
+func (s *SyncedRepo) prepare(pkg Package) (Addition, *kudo.PackageVersion, []byte, error) {
 	log.WithField("repository", s.URL).
-		WithField("tarball", tarballName).
+		WithField("package", pkg.String()).
 		Debug("Creating operator package")
 
-	tarball, err := s.fs.OpenFile(tarballName, os.O_WRONLY|os.O_CREATE, 0644)
+	var buf bytes.Buffer
+
+	// Path needs to be an empty string, otherwise wrong filenames will be created
+	if err := writer.TgzDir(pkg, "", &buf); err != nil {
+		return Addition{}, nil, nil, fmt.Errorf("failed to tar operator package %q: %v", pkg.String(), err)
+	}
+
+	entry, err := s.packageVersion(buf.Bytes())
 	if err != nil {
-		return "", fmt.Errorf("failed to create operator package %q : %v", tarballName, err)
+		return Addition{}, nil, nil, fmt.Errorf("failed to index operator package %q: %v", pkg.String(), err)
 	}
 
+	addition := Addition{
+		Tarball: tarballName(entry.Metadata),
+		Digest:  entry.Digest,
+	}
+
+	if previous := s.entry(entry.Metadata); previous != nil {
+		addition.PreviousDigest = previous.Digest
+	}
+
+	return addition, entry, buf.Bytes(), nil
+}
+
+// Remove stages the removal of an operator package from the repository.
+// The package is removed from the index and its tarball is deleted once
+// 'Commit' is called.
+func (s *SyncedRepo) Remove(pkg Package) (string, error) {
+	var entry *kudo.PackageVersion
+
+	for _, e := range s.index[pkg.OperatorName] {
+		if pkg.Equal(entryPackage(e)) {
+			entry = e
+			break
+		}
+	}
+
+	if entry == nil {
+		return "", fmt.Errorf("operator package %q isn't in the repository", pkg.String())
+	}
+
+	tarballName := tarballName(entry.Metadata)
+
+	s.begin()
+
+	if tempName, ok := s.staged[tarballName]; ok {
+		s.removeFile(tempName)
+		delete(s.staged, tarballName)
+	}
+
+	entries := kudo.PackageVersions{}
+
+	for _, e := range s.index[entry.Name] {
+		if e != entry {
+			entries = append(entries, e)
+		}
+	}
+
+	if len(entries) == 0 {
+		delete(s.index, entry.Name)
+	} else {
+		s.index[entry.Name] = entries
+	}
+
+	s.removed[tarballName] = true
+
+	return tarballName, nil
+}
+
+// Packages lists the versions of all packages in the repository.
+// The returned packages only contain version information, their file
+// systems are nil.
+func (s SyncedRepo) Packages() []Package {
+	packages := []Package{}
+
+	for _, entries := range s.index {
+		for _, entry := range entries {
+			packages = append(packages, entryPackage(entry))
+		}
+	}
+
+	sort.Slice(packages, func(i, j int) bool {
+		return packages[i].String() < packages[j].String()
+	})
+
+	return packages
+}
+
+// begin prepares staging of changes. The current index is kept to be
+// restored by 'Rollback'.
+func (s *SyncedRepo) begin() {
+	if s.committed == nil {
+		s.committed = map[string]kudo.PackageVersions{}
+
+		for name, entries := range s.index {
+			s.committed[name] = entries
+		}
+	}
+
+	if s.staged == nil {
+		s.staged = map[string]string{}
+	}
+
+	if s.removed == nil {
+		s.removed = map[string]bool{}
+	}
+}
+
+// Commit moves staged packages into place and writes a new index file
+// containing all packages of the repository, if packages have been staged.
+// Tarballs of removed packages are deleted after the new index file has been
+// written.
+// Packages that are already indexed keep their index entries and digests,
+// only tarballs unknown to the index are read.
+// The index file is replaced after all tarballs are in place, hence it never
+// references missing tarballs. Tarballs replaced by staged tarballs are copied
+// to backups and then atomically replaced, the backups are kept until the
+// index file is replaced and restored if the commit fails.
+func (s *SyncedRepo) Commit() (err error) {
+	if len(s.staged) == 0 && len(s.removed) == 0 {
+		return nil
+	}
+
+	var (
+		tempIndex string
+		renamed   []string
+	)
+
+	// Tarballs replaced by staged tarballs, mapped to the names of their
+	// backups.
+	backups := map[string]string{}
+
+	// If any of the following steps fails, the repository is restored:
+	// Replaced tarballs are restored from their backups, tarballs that didn't
+	// exist before are removed again, the new index file and all remaining
+	// staged tarballs are discarded.
 	defer func() {
-		if cerr := tarball.Close(); cerr != nil {
-			err = cerr
+		if err == nil {
+			return
+		}
+
+		for _, tarball := range renamed {
+			if _, ok := backups[tarball]; !ok {
+				s.removeFile(tarball)
+			}
+		}
+
+		for tarball, backup := range backups {
+			if rerr := s.fs.Rename(backup, tarball); rerr != nil {
+				log.WithField("repository", s.URL).
+					WithField("tarball", tarball).
+					WithField("backup", backup).
+					WithError(rerr).
+					Error("Failed to restore replaced operator package")
+			}
+		}
+
+		if tempIndex != "" {
+			s.removeFile(tempIndex)
 		}
+
+		if serr := syncDir(s.fs); serr != nil {
+			log.WithField("repository", s.URL).
+				WithError(serr).
+				Warn("Failed to sync repository directory")
+		}
+
+		s.Rollback()
 	}()
 
-	// Path needs to be an empty string, otherwise wrong filenames will be created
-	if err := writer.TgzDir(pkg, "", tarball); err != nil {
-		return "", fmt.Errorf("failed to tar operator package %q: %v", tarballName, err)
+	tarballs, err := afero.Glob(s.fs, "*.tgz")
+	if err != nil {
+		return fmt.Errorf("failed to list operator packages: %v", err)
 	}
 
-	now := time.Now()
+	existing := map[string]bool{}
+
+	for _, tarball := range tarballs {
+		existing[filepath.Base(tarball)] = true
+	}
 
-	newIndex, err := kudo.IndexDirectory(s.fs, string(filepath.Separator), s.URL, &now)
+	newIndex, err := s.newIndex(tarballs)
 	if err != nil {
-		return "", fmt.Errorf("failed to create new index: %v", err)
+		return err
 	}
 
-	s.index = newIndex.Entries
+	var indexFile bytes.Buffer
+
+	if err := newIndex.Write(&indexFile); err != nil {
+		return fmt.Errorf("failed to create new index: %v", err)
+	}
+
+	tempIndex, err = writeTempFile(s.fs, "index.yaml", indexFile.Bytes())
+	if err != nil {
+		return fmt.Errorf("failed to write new index: %v", err)
+	}
+
+	for tarball, tempName := range s.staged {
+		// The replaced tarball stays in place until the staged tarball
+		// replaces it, readers never see it missing.
+		if existing[tarball] {
+			backup := tempName + backupSuffix
+
+			if err := copyFile(s.fs, tarball, backup); err != nil {
+				return fmt.Errorf("failed to back up operator package %q: %v", tarball, err)
+			}
+
+			backups[tarball] = backup
+		}
+
+		if err := s.fs.Rename(tempName, tarball); err != nil {
+			return fmt.Errorf("failed to move operator package %q into place: %v", tarball, err)
+		}
+
+		delete(s.staged, tarball)
+
+		renamed = append(renamed, tarball)
+	}
+
+	// The tarballs have to be in place on disk before the index references
+	// them.
+	if err := syncDir(s.fs); err != nil {
+		return fmt.Errorf("failed to sync repository directory: %v", err)
+	}
 
 	log.WithField("repository", s.URL).
-		WithField("tarball", tarballName).
+		WithField("added", len(renamed)).
+		WithField("removed", len(s.removed)).
 		Debug("Writing new index file")
 
 	// The repository file system is the source of truth.
-	// We don't merge an existing index but overwrite instead.
-	if err := newIndex.WriteFile(s.fs, "index.yaml"); err != nil {
-		return "", fmt.Errorf("failed to write new index: %v", err)
+	// Index entries of removed tarballs are dropped.
+	if err := s.fs.Rename(tempIndex, "index.yaml"); err != nil {
+		return fmt.Errorf("failed to write new index: %v", err)
 	}
 
-	return tarballName, nil
+	// The new index is in place, the backups are no longer needed.
+	for _, backup := range backups {
+		s.removeFile(backup)
+	}
+
+	// The index doesn't reference removed tarballs anymore. If removing them
+	// fails, they are unreferenced but don't affect the repository.
+	for tarball := range s.removed {
+		log.WithField("repository", s.URL).
+			WithField("tarball", tarball).
+			Debug("Removing operator package")
+
+		s.removeFile(tarball)
+	}
+
+	// The commit succeeded, a failed sync only risks losing it on a crash.
+	if err := syncDir(s.fs); err != nil {
+		log.WithField("repository", s.URL).
+			WithError(err).
+			Warn("Failed to sync repository directory")
+	}
+
+	s.index = newIndex.Entries
+	s.staged = nil
+	s.removed = nil
+	s.committed = nil
+
+	return nil
+}
+
+// Rollback discards all staged packages and removals.
+func (s *SyncedRepo) Rollback() {
+	for tarball, tempName := range s.staged {
+		log.WithField("repository", s.URL).
+			WithField("tarball", tarball).
+			Debug("Discarding staged operator package")
+
+		s.removeFile(tempName)
+	}
+
+	if s.committed != nil {
+		s.index = s.committed
+	}
+
+	s.staged = nil
+	s.removed = nil
+	s.committed = nil
+}
+
+// newIndex creates an index of the repository's tarballs including staged
+// tarballs.
+func (s *SyncedRepo) newIndex(tarballs []string) (*kudo.IndexFile, error) {
+	indexed := map[string]*kudo.PackageVersion{}
+
+	for _, entries := range s.index {
+		for _, entry := range entries {
+			indexed[tarballName(entry.Metadata)] = entry
+		}
+	}
+
+	for tarball := range s.staged {
+		if _, ok := indexed[tarball]; !ok {
+			return nil, fmt.Errorf("staged operator package %q isn't indexed", tarball)
+		}
+
+		tarballs = append(tarballs, tarball)
+	}
+
+	now := time.Now()
+
+	newIndex := &kudo.IndexFile{
+		APIVersion: "v1",
+		Generated:  &now,
+	}
+
+	seen := map[string]bool{}
+
+	for _, tarball := range tarballs {
+		tarball = filepath.Base(tarball)
+
+		if seen[tarball] || s.removed[tarball] {
+			continue
+		}
+
+		seen[tarball] = true
+
+		entry, ok := indexed[tarball]
+		if ok {
+			// The repository URL may have changed.
+			entry.URLs = []string{s.tarballURL(entry.Metadata)}
+		} else {
+			log.WithField("repository", s.URL).
+				WithField("tarball", tarball).
+				Debug("Indexing unknown operator package")
+
+			var err error
+
+			entry, err = s.readPackageVersion(tarball)
+			if err != nil {
+				log.WithField("repository", s.URL).
+					WithField("tarball", tarball).
+					WithError(err).
+					Warn("Skipping invalid operator package")
+
+				continue
+			}
+		}
+
+		if err := newIndex.AddPackageVersion(entry); err != nil {
+			return nil, fmt.Errorf("failed to create new index: %v", err)
+		}
+	}
+
+	// Same order as used by 'kudo.IndexFile.WriteFile'.
+	for _, entries := range newIndex.Entries {
+		sort.Sort(sort.Reverse(entries))
+	}
+
+	return newIndex, nil
+}
+
+func (s *SyncedRepo) removeFile(name string) {
+	if err := s.fs.Remove(name); err != nil && !os.IsNotExist(err) {
+		log.WithField("repository", s.URL).
+			WithField("file", name).
+			WithError(err).
+			Warn("Failed to remove file")
+	}
+}
+
+// entry returns the index entry of a package version, or nil if the version
+// isn't indexed.
+func (s *SyncedRepo) entry(metadata *kudo.Metadata) *kudo.PackageVersion {
+	for _, e := range s.index[metadata.Name] {
+		if e.OperatorVersion == metadata.OperatorVersion && e.AppVersion == metadata.AppVersion {
+			return e
+		}
+	}
+
+	return nil
+}
+
+// setEntry adds an index entry, replacing an existing entry of the same
+// package version.
+func (s *SyncedRepo) setEntry(entry *kudo.PackageVersion) {
+	if s.index == nil {
+		s.index = map[string]kudo.PackageVersions{}
+	}
+
+	entries := kudo.PackageVersions{}
+
+	for _, e := range s.index[entry.Name] {
+		if e.OperatorVersion != entry.OperatorVersion || e.AppVersion != entry.AppVersion {
+			entries = append(entries, e)
+		}
+	}
+
+	s.index[entry.Name] = append(entries, entry)
+}
+
+func (s *SyncedRepo) readPackageVersion(tarball string) (*kudo.PackageVersion, error) {
+	content, err := afero.ReadFile(s.fs, tarball)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.packageVersion(content)
+}
+
+// packageVersion creates an index entry for a package tarball.
+func (s *SyncedRepo) packageVersion(tarball []byte) (*kudo.PackageVersion, error) {
+	digest, err := files.Sha256Sum(bytes.NewReader(tarball))
+	if err != nil {
+		return nil, err
+	}
+
+	pf, err := reader.ParseTgz(bytes.NewReader(tarball))
+	if err != nil {
+		return nil, err
+	}
+
+	return kudo.ToPackageVersion(pf, digest, s.URL), nil
+}
+
+func (s *SyncedRepo) tarballURL(metadata *kudo.Metadata) string {
+	// Same default as used by 'kudo.ToPackageVersion'.
+	url := s.URL
+	if url == "" {
+		url = "http://localhost/"
+	}
+
+	if url[len(url)-1:] != "/" {
+		url += "/"
+	}
+
+	return url + tarballName(metadata)
+}
+
+func tarballName(metadata *kudo.Metadata) string {
+	if metadata.AppVersion == "" {
+		return fmt.Sprintf("%s-%s.tgz", metadata.Name, metadata.OperatorVersion)
+	}
+
+	return fmt.Sprintf("%s-%s_%s.tgz", metadata.Name, metadata.AppVersion, metadata.OperatorVersion)
+}
+
+// backupSuffix is appended to the names of the temporary files of staged
+// tarballs to name the backups of the tarballs they replace.
+const backupSuffix = ".backup"
+
+// recoverTempFiles cleans up after an interrupted commit. Backups of replaced
+// tarballs are restored if 'index' still references them, i.e. if the commit
+// was interrupted before the index file was replaced. All other temporary
+// files of tarballs and the index file are removed.
+func recoverTempFiles(fs afero.Fs, repoURL string, index map[string]kudo.PackageVersions) error {
+	backups, err := afero.Glob(fs, ".*.tgz.*"+backupSuffix)
+	if err != nil {
+		return fmt.Errorf("failed to list backups: %v", err)
+	}
+
+	digests := map[string]string{}
+
+	for _, entries := range index {
+		for _, entry := range entries {
+			digests[tarballName(entry.Metadata)] = entry.Digest
+		}
+	}
+
+	for _, backup := range backups {
+		if err := restoreBackup(fs, repoURL, backup, digests); err != nil {
+			return err
+		}
+	}
+
+	if err := removeTempFiles(fs, repoURL); err != nil {
+		return err
+	}
+
+	if len(backups) > 0 {
+		if err := syncDir(fs); err != nil {
+			return fmt.Errorf("failed to sync repository directory: %v", err)
+		}
+	}
+
+	return nil
+}
+
+// restoreBackup restores the tarball backed up as 'backup' if the index
+// references the backup's content, given as 'digests' of tarball names, and
+// not the content of the tarball.
+func restoreBackup(fs afero.Fs, repoURL, backup string, digests map[string]string) error {
+	// Backups are named like ".<tarball>.<random>.backup".
+	name := strings.TrimSuffix(strings.TrimPrefix(filepath.Base(backup), "."), backupSuffix)
+	tarball := filepath.Join(filepath.Dir(backup), name[:strings.LastIndex(name, ".")])
+
+	digest, ok := digests[filepath.Base(tarball)]
+	if !ok {
+		return nil
+	}
+
+	backupDigest, err := fileDigest(fs, backup)
+	if err != nil {
+		return fmt.Errorf("failed to read backup %q: %v", backup, err)
+	}
+
+	if backupDigest != digest {
+		return nil
+	}
+
+	tarballDigest, err := fileDigest(fs, tarball)
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to read operator package %q: %v", tarball, err)
+	}
+
+	if tarballDigest == digest {
+		return nil
+	}
+
+	log.WithField("repository", repoURL).
+		WithField("tarball", tarball).
+		WithField("backup", backup).
+		Info("Restoring operator package replaced by an interrupted update")
+
+	if err := fs.Rename(backup, tarball); err != nil {
+		return fmt.Errorf("failed to restore operator package %q: %v", tarball, err)
+	}
+
+	return nil
+}
+
+// fileDigest returns the SHA256 digest of a file.
+func fileDigest(fs afero.Fs, name string) (digest string, err error) {
+	f, err := fs.Open(name)
+	if err != nil {
+		return "", err
+	}
+
+	defer func() {
+		if cerr := f.Close(); cerr != nil && err == nil {
+			err = cerr
+		}
+	}()
+
+	return files.Sha256Sum(f)
+}
+
+// copyFile copies the file 'name' to 'target', which is replaced atomically.
+func copyFile(fs afero.Fs, name, target string) error {
+	content, err := afero.ReadFile(fs, name)
+	if err != nil {
+		return err
+	}
+
+	tempName, err := writeTempFile(fs, name, content)
+	if err != nil {
+		return err
+	}
+
+	if err := fs.Rename(tempName, target); err != nil {
+		_ = fs.Remove(tempName)
+		return err
+	}
+
+	return nil
+}
+
+// removeTempFiles removes the temporary files and backups of tarballs and the
+// index file left behind by an interrupted commit.
+func removeTempFiles(fs afero.Fs, repoURL string) error {
+	for _, pattern := range []string{".*.tgz.*", ".index.yaml.*"} {
+		tempFiles, err := afero.Glob(fs, pattern)
+		if err != nil {
+			return fmt.Errorf("failed to list temporary files: %v", err)
+		}
+
+		for _, tempFile := range tempFiles {
+			log.WithField("repository", repoURL).
+				WithField("file", tempFile).
+				Info("Removing temporary file of an interrupted update")
+
+			if err := fs.Remove(tempFile); err != nil && !os.IsNotExist(err) {
+				return fmt.Errorf("failed to remove temporary file %q: %v", tempFile, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// syncDir syncs the root directory of 'fs' to disk, which persists the
+// renames of its files.
+func syncDir(fs afero.Fs) (err error) {
+	dir, err := fs.Open(".")
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		if cerr := dir.Close(); cerr != nil && err == nil {
+			err = cerr
+		}
+	}()
+
+	return dir.Sync()
+}
+
+// writeTempFile writes data to a new temporary file next to 'name' and syncs
+// it to disk. The temporary file can then be atomically renamed to 'name'.
+// Temporary files are hidden and don't have the extension of 'name'.
+func writeTempFile(fs afero.Fs, name string, data []byte) (tempName string, err error) {
+	f, err := afero.TempFile(fs, filepath.Dir(name), fmt.Sprintf(".%s.", filepath.Base(name)))
+	if err != nil {
+		return "", err
+	}
+
+	tempName = f.Name()
+
+	defer func() {
+		if cerr := f.Close(); cerr != nil && err == nil {
+			err = cerr
+		}
+
+		if err != nil {
+			_ = fs.Remove(tempName)
+		}
+	}()
+
+	// Temporary files are only readable by their owner, but the repository
+	// is usually served by other users.
+	if err := fs.Chmod(tempName, 0644); err != nil {
+		return "", err
+	}
+
+	if _, err := f.Write(data); err != nil {
+		return "", err
+	}
+
+	if err := f.Sync(); err != nil {
+		return "", err
+	}
+
+	return tempName, nil
 }
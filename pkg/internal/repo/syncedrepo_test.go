@@ -1,11 +1,14 @@
 package repo
 
 import (
+	"errors"
 	"fmt"
 	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/Masterminds/semver/v3"
+	"github.com/kudobuilder/kudo/pkg/kudoctl/files"
 	kudo "github.com/kudobuilder/kudo/pkg/kudoctl/util/repo"
 	"github.com/spf13/afero"
 	"github.com/stretchr/testify/assert"
@@ -67,6 +70,262 @@ func TestContains(t *testing.T) {
 }
 
 func TestAdd(t *testing.T) {
+	repo, _ := newTestRepo(t)
+
+	pkg := createPackage(t, `name: foo
+operatorVersion: "1.0.0"
+appVersion: "1.0.0"
+`)
+
+	assert.False(t, repo.Contains(pkg))
+
+	addition, err := repo.Add(pkg)
+	assert.NoError(t, err)
+
+	assert.Equal(t, fmt.Sprintf("%s.tgz", pkg.String()), addition.Tarball)
+	assert.False(t, addition.Replaced())
+	assert.True(t, addition.Changed())
+
+	assert.True(t, repo.Contains(pkg))
+}
+
+func TestCommit(t *testing.T) {
+	repo, repoFs := newTestRepo(t)
+
+	foo1 := createPackage(t, `name: foo
+operatorVersion: "1.0.0"
+`)
+
+	_, err := repo.Add(foo1)
+	assert.NoError(t, err)
+
+	// The index isn't written before the commit.
+	exists, err := afero.Exists(repoFs, "index.yaml")
+	assert.NoError(t, err)
+	assert.False(t, exists)
+
+	assert.NoError(t, repo.Commit())
+
+	foo1Digest := repo.index["foo"][0].Digest
+	assert.NotEmpty(t, foo1Digest)
+
+	// Existing packages aren't read again, they keep their digest even if
+	// their tarball changed.
+	assert.NoError(t, afero.WriteFile(repoFs, "foo-1.0.0.tgz", []byte("changed"), 0644))
+
+	repo, err = NewSyncedRepo(repoFs, "https://example.org")
+	assert.NoError(t, err)
+
+	assert.True(t, repo.Contains(foo1))
+
+	foo2 := createPackage(t, `name: foo
+operatorVersion: "2.0.0"
+`)
+
+	_, err = repo.Add(foo2)
+	assert.NoError(t, err)
+	assert.NoError(t, repo.Commit())
+
+	indexFile, err := afero.ReadFile(repoFs, "index.yaml")
+	assert.NoError(t, err)
+
+	index, err := kudo.ParseIndexFile(indexFile)
+	assert.NoError(t, err)
+
+	assert.Len(t, index.Entries["foo"], 2)
+
+	for _, entry := range index.Entries["foo"] {
+		assert.Equal(t, []string{fmt.Sprintf("https://example.org/foo-%s.tgz", entry.OperatorVersion)}, entry.URLs)
+
+		if entry.OperatorVersion == "1.0.0" {
+			assert.Equal(t, foo1Digest, entry.Digest)
+		} else {
+			assert.NotEqual(t, foo1Digest, entry.Digest)
+		}
+	}
+}
+
+func TestCommitNonCanonicalVersion(t *testing.T) {
+	repo, repoFs := newTestRepo(t)
+
+	pkg := createPackage(t, `name: foo
+operatorVersion: "0.1"
+appVersion: "1.0"
+`)
+
+	addition, err := repo.Add(pkg)
+	assert.NoError(t, err)
+
+	// Tarballs are named after the versions of the package metadata, like
+	// their URLs in the index.
+	assert.Equal(t, "foo-1.0_0.1.tgz", addition.Tarball)
+
+	assert.NoError(t, repo.Commit())
+
+	exists, err := afero.Exists(repoFs, "foo-1.0_0.1.tgz")
+	assert.NoError(t, err)
+	assert.True(t, exists)
+
+	tempFiles, err := afero.Glob(repoFs, ".*")
+	assert.NoError(t, err)
+	assert.Empty(t, tempFiles)
+
+	assert.Equal(t, []string{"https://example.org/foo-1.0_0.1.tgz"}, repo.index["foo"][0].URLs)
+
+	repo, err = NewSyncedRepo(repoFs, "https://example.org")
+	assert.NoError(t, err)
+	assert.True(t, repo.Contains(pkg))
+
+	addition, err = repo.Add(pkg)
+	assert.NoError(t, err)
+	assert.False(t, addition.Changed())
+}
+
+// failingRenameFs fails renames to 'target' and records the names of all
+// renamed files.
+type failingRenameFs struct {
+	afero.Fs
+
+	target  string
+	renamed *[]string
+}
+
+func (fs failingRenameFs) Rename(oldname, newname string) error {
+	*fs.renamed = append(*fs.renamed, oldname)
+
+	if newname == fs.target {
+		return errors.New("rename failed")
+	}
+
+	return fs.Fs.Rename(oldname, newname)
+}
+
+func TestCommitRestore(t *testing.T) {
+	repo, repoFs := newTestRepo(t)
+
+	large := createPackage(t, `name: foo
+operatorVersion: "1.0.0"
+description: "`+strings.Repeat("large ", 1000)+`"
+`)
+
+	first, err := repo.Add(large)
+	assert.NoError(t, err)
+	assert.NoError(t, repo.Commit())
+
+	indexFile, err := afero.ReadFile(repoFs, "index.yaml")
+	assert.NoError(t, err)
+
+	small := createPackage(t, `name: foo
+operatorVersion: "1.0.0"
+`)
+
+	bar := createPackage(t, `name: bar
+operatorVersion: "1.0.0"
+`)
+
+	// Replacing the index fails after the tarballs have been moved into
+	// place.
+	renamed := []string{}
+	repo.fs = failingRenameFs{Fs: repoFs, target: "index.yaml", renamed: &renamed}
+
+	for _, pkg := range []Package{small, bar} {
+		_, err := repo.Add(pkg)
+		assert.NoError(t, err)
+	}
+
+	assert.Error(t, repo.Commit())
+
+	// Published tarballs are never moved away, only replaced.
+	for _, name := range renamed {
+		assert.True(t, strings.HasPrefix(filepath.Base(name), "."), name)
+	}
+
+	// The replaced tarball is restored, the added tarball is removed and the
+	// index is unchanged.
+	tarball, err := repoFs.Open(first.Tarball)
+	assert.NoError(t, err)
+
+	defer tarball.Close()
+
+	digest, err := files.Sha256Sum(tarball)
+	assert.NoError(t, err)
+	assert.Equal(t, first.Digest, digest)
+
+	entries, err := afero.ReadDir(repoFs, "")
+	assert.NoError(t, err)
+
+	names := []string{}
+	for _, f := range entries {
+		names = append(names, f.Name())
+	}
+
+	assert.ElementsMatch(t, []string{"index.yaml", first.Tarball}, names)
+
+	actual, err := afero.ReadFile(repoFs, "index.yaml")
+	assert.NoError(t, err)
+	assert.Equal(t, indexFile, actual)
+}
+
+func TestNewSyncedRepoRemovesTempFiles(t *testing.T) {
+	_, repoFs := newTestRepo(t)
+
+	for _, name := range []string{".foo-1.0.0.tgz.123", ".foo-1.0.0.tgz.123.backup", ".index.yaml.456", "foo-1.0.0.tgz"} {
+		assert.NoError(t, afero.WriteFile(repoFs, name, []byte{}, 0644))
+	}
+
+	_, err := NewSyncedRepo(repoFs, "https://example.org")
+	assert.NoError(t, err)
+
+	files, err := afero.ReadDir(repoFs, "")
+	assert.NoError(t, err)
+	assert.Len(t, files, 1)
+	assert.Equal(t, "foo-1.0.0.tgz", files[0].Name())
+}
+
+func TestNewSyncedRepoRestoresBackups(t *testing.T) {
+	repo, repoFs := newTestRepo(t)
+
+	pkg := createPackage(t, `name: foo
+operatorVersion: "1.0.0"
+`)
+
+	addition, err := repo.Add(pkg)
+	assert.NoError(t, err)
+	assert.NoError(t, repo.Commit())
+
+	committed, err := afero.ReadFile(repoFs, addition.Tarball)
+	assert.NoError(t, err)
+
+	// A commit replacing the tarball was interrupted before the index file was
+	// replaced.
+	assert.NoError(t, afero.WriteFile(repoFs, ".foo-1.0.0.tgz.123.backup", committed, 0644))
+	assert.NoError(t, afero.WriteFile(repoFs, addition.Tarball, []byte("replaced"), 0644))
+
+	_, err = NewSyncedRepo(repoFs, "https://example.org")
+	assert.NoError(t, err)
+
+	restored, err := afero.ReadFile(repoFs, addition.Tarball)
+	assert.NoError(t, err)
+	assert.Equal(t, committed, restored)
+
+	// A commit was interrupted after the index file was replaced, the backup
+	// isn't indexed anymore.
+	assert.NoError(t, afero.WriteFile(repoFs, ".foo-1.0.0.tgz.123.backup", []byte("previous"), 0644))
+
+	_, err = NewSyncedRepo(repoFs, "https://example.org")
+	assert.NoError(t, err)
+
+	restored, err = afero.ReadFile(repoFs, addition.Tarball)
+	assert.NoError(t, err)
+	assert.Equal(t, committed, restored)
+
+	tempFiles, err := afero.Glob(repoFs, ".*")
+	assert.NoError(t, err)
+	assert.Empty(t, tempFiles)
+}
+
+// newTestRepo creates an empty repository in a memory file system.
+func newTestRepo(t *testing.T) (*SyncedRepo, afero.Fs) {
 	// Using 'MemMapFs' with the default base path causes all kinds of trouble.
 	// To avoid potential issues, all files are created in directories and
 	// 'BasePathFs' is used to point to a different base path.
@@ -77,32 +336,138 @@ func TestAdd(t *testing.T) {
 	assert.NoError(t, repoFs.Mkdir(repoDir, 0755))
 	repoFs = afero.NewBasePathFs(repoFs, repoDir)
 
-	repo := SyncedRepo{
-		fs: repoFs,
-	}
+	repo, err := NewSyncedRepo(repoFs, "https://example.org")
+	assert.NoError(t, err)
+
+	return repo, repoFs
+}
 
+func createPackage(t *testing.T, operator string) Package {
+	// Using 'MemMapFs' with the default base path causes all kinds of trouble.
+	// To avoid potential issues, all files are created in directories and
+	// 'BasePathFs' is used to point to a different base path.
 	pkgFs := afero.NewMemMapFs()
 
 	operatorDir := filepath.Join(string(filepath.Separator), "operator")
 
 	assert.NoError(t, pkgFs.Mkdir(operatorDir, 0755))
-	assert.NoError(t, afero.WriteFile(pkgFs, filepath.Join(operatorDir, "operator.yaml"), []byte(`name: foo
-operatorVersion: "1.0.0"
-appVersion: "1.0.0"
-`), 0644))
+	assert.NoError(t, afero.WriteFile(pkgFs, filepath.Join(operatorDir, "operator.yaml"), []byte(operator), 0644))
 	assert.NoError(t, afero.WriteFile(pkgFs, filepath.Join(operatorDir, "params.yaml"), []byte{}, 0644))
 
-	pkgFs = afero.NewBasePathFs(pkgFs, operatorDir)
+	pkg, err := NewPackage(afero.NewBasePathFs(pkgFs, operatorDir))
+	assert.NoError(t, err)
 
-	pkg, err := NewPackage(pkgFs)
+	return pkg
+}
+
+func TestRollback(t *testing.T) {
+	repo, repoFs := newTestRepo(t)
+
+	pkg := createPackage(t, `name: foo
+operatorVersion: "1.0.0"
+`)
+
+	_, err := repo.Add(pkg)
 	assert.NoError(t, err)
+	assert.True(t, repo.Contains(pkg))
 
+	// Staged packages aren't visible before the commit.
+	tarballs, err := afero.Glob(repoFs, "*.tgz")
+	assert.NoError(t, err)
+	assert.Empty(t, tarballs)
+
+	repo.Rollback()
 	assert.False(t, repo.Contains(pkg))
 
-	tarball, err := repo.Add(pkg)
+	files, err := afero.ReadDir(repoFs, "")
 	assert.NoError(t, err)
+	assert.Empty(t, files)
 
-	assert.Equal(t, fmt.Sprintf("%s.tgz", pkg.String()), tarball)
+	// Nothing is left to commit.
+	assert.NoError(t, repo.Commit())
 
-	assert.True(t, repo.Contains(pkg))
+	exists, err := afero.Exists(repoFs, "index.yaml")
+	assert.NoError(t, err)
+	assert.False(t, exists)
+}
+
+func TestAddReplace(t *testing.T) {
+	repo, repoFs := newTestRepo(t)
+
+	large := createPackage(t, `name: foo
+operatorVersion: "1.0.0"
+description: "`+strings.Repeat("large ", 1000)+`"
+`)
+
+	first, err := repo.Add(large)
+	assert.NoError(t, err)
+	assert.NoError(t, repo.Commit())
+
+	// Adding the same content again doesn't replace the package.
+	same, err := repo.Add(large)
+	assert.NoError(t, err)
+	assert.True(t, same.Replaced())
+	assert.False(t, same.Changed())
+
+	small := createPackage(t, `name: foo
+operatorVersion: "1.0.0"
+`)
+
+	second, err := repo.Add(small)
+	assert.NoError(t, err)
+	assert.NoError(t, repo.Commit())
+
+	assert.True(t, second.Replaced())
+	assert.True(t, second.Changed())
+	assert.Equal(t, first.Digest, second.PreviousDigest)
+
+	// The smaller tarball replaces the larger one completely.
+	tarball, err := repoFs.Open(second.Tarball)
+	assert.NoError(t, err)
+
+	defer tarball.Close()
+
+	digest, err := files.Sha256Sum(tarball)
+	assert.NoError(t, err)
+	assert.Equal(t, second.Digest, digest)
+}
+
+func TestRemove(t *testing.T) {
+	repo, repoFs := newTestRepo(t)
+
+	foo1 := createPackage(t, `name: foo
+operatorVersion: "1.0.0"
+`)
+	foo2 := createPackage(t, `name: foo
+operatorVersion: "2.0.0"
+`)
+
+	for _, pkg := range []Package{foo1, foo2} {
+		_, err := repo.Add(pkg)
+		assert.NoError(t, err)
+	}
+
+	assert.NoError(t, repo.Commit())
+
+	tarball, err := repo.Remove(foo1)
+	assert.NoError(t, err)
+	assert.Equal(t, "foo-1.0.0.tgz", tarball)
+	assert.False(t, repo.Contains(foo1))
+
+	// The tarball is only removed on commit.
+	exists, err := afero.Exists(repoFs, tarball)
+	assert.NoError(t, err)
+	assert.True(t, exists)
+
+	assert.NoError(t, repo.Commit())
+
+	exists, err = afero.Exists(repoFs, tarball)
+	assert.NoError(t, err)
+	assert.False(t, exists)
+
+	assert.Len(t, repo.Packages(), 1)
+	assert.True(t, foo2.Equal(repo.Packages()[0]))
+
+	_, err = repo.Remove(foo1)
+	assert.Error(t, err)
 }
@@ -0,0 +1,376 @@
+package archive
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/klauspost/compress/zstd"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/afero"
+)
+
+// Limits restrict the extraction of archives. Zero values mean no limit.
+type Limits struct {
+	// TotalSize is the maximum size of all extracted files in bytes.
+	TotalSize int64
+
+	// Entries is the maximum number of entries of an archive.
+	Entries int
+
+	// FileSize is the maximum size of a single extracted file in bytes.
+	FileSize int64
+}
+
+// DefaultLimits returns the limits used unless configured otherwise. They are
+// generous for operator packages, but prevent archives from exhausting memory.
+func DefaultLimits() Limits {
+	return Limits{
+		TotalSize: 256 << 20,
+		Entries:   20000,
+		FileSize:  64 << 20,
+	}
+}
+
+// Magic bytes identifying archive formats.
+const (
+	magicGzip = "\x1f\x8b"
+	magicZstd = "\x28\xb5\x2f\xfd"
+	magicZip  = "PK\x03\x04"
+
+	// Empty zip archives only consist of an end of central directory record.
+	magicZipEmpty = "PK\x05\x06"
+
+	// Tar archives have a magic string at offset 257 of their first header.
+	magicTarOffset = 257
+	magicTar       = "ustar"
+)
+
+// Extract extracts an operator package archive into an in-memory file system.
+// Tar archives, either uncompressed or compressed with gzip or zstd, and zip
+// archives are supported. Their format is detected from their content.
+// The returned file system is rooted at the top level of the archive, or at
+// its only top-level directory if the package is wrapped in a single
+// directory.
+// If 'subdirectory' is set, the file system is rooted at this directory
+// instead. It is looked up at the top level of the archive first, and in the
+// only top-level directory if it doesn't exist there.
+// Extraction fails if an entry would be written outside of the file system or
+// if the archive exceeds 'limits'.
+func Extract(data []byte, subdirectory string, limits Limits) (afero.Fs, error) {
+	e, err := newExtractor(limits)
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case bytes.HasPrefix(data, []byte(magicGzip)):
+		gzr, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, fmt.Errorf("failed to unzip tarball: %v", err)
+		}
+
+		err = e.extractTar(gzr)
+		if cerr := gzr.Close(); cerr != nil && err == nil {
+			err = fmt.Errorf("failed to close tarball: %v", cerr)
+		}
+
+		if err != nil {
+			return nil, err
+		}
+	case bytes.HasPrefix(data, []byte(magicZstd)):
+		zr, err := zstd.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, fmt.Errorf("failed to decompress zstd tarball: %v", err)
+		}
+
+		err = e.extractTar(zr)
+
+		zr.Close()
+
+		if err != nil {
+			return nil, err
+		}
+	case bytes.HasPrefix(data, []byte(magicZip)) || bytes.HasPrefix(data, []byte(magicZipEmpty)):
+		if err := e.extractZip(data); err != nil {
+			return nil, err
+		}
+	case len(data) > magicTarOffset+len(magicTar) &&
+		string(data[magicTarOffset:magicTarOffset+len(magicTar)]) == magicTar:
+		if err := e.extractTar(bytes.NewReader(data)); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, errors.New("unknown archive format, expected a tar, tar.gz, tar.zst or zip archive")
+	}
+
+	root, err := unwrap(e.fs, e.dir)
+	if err != nil {
+		return nil, err
+	}
+
+	if subdirectory != "" {
+		root, err = findSubdirectory(e.fs, e.dir, root, subdirectory)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return afero.NewBasePathFs(e.fs, root), nil
+}
+
+// extractor writes archive entries into an in-memory file system and enforces
+// limits.
+type extractor struct {
+	fs  afero.Fs
+	dir string
+
+	limits    Limits
+	entries   int
+	totalSize int64
+}
+
+func newExtractor(limits Limits) (*extractor, error) {
+	fs := afero.NewMemMapFs()
+
+	// Using 'MemMapFs' with the default base path causes all kinds of trouble.
+	// To avoid potential issues, all files are created in directories and
+	// 'BasePathFs' is used to point to a different base path.
+	operatorDir := filepath.Join(string(filepath.Separator), "operator")
+
+	if err := fs.Mkdir(operatorDir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create operator directory: %v", err)
+	}
+
+	return &extractor{fs: fs, dir: operatorDir, limits: limits}, nil
+}
+
+func (e *extractor) extractTar(r io.Reader) error {
+	tr := tar.NewReader(r)
+
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+
+		if err != nil {
+			return fmt.Errorf("failed to read tarball entry: %v", err)
+		}
+
+		if err := e.count(); err != nil {
+			return err
+		}
+
+		switch hdr.Typeflag {
+		case tar.TypeReg:
+			if err := e.writeFile(hdr.Name, hdr.FileInfo().Mode(), hdr.Size, tr); err != nil {
+				return err
+			}
+		case tar.TypeDir:
+			if err := e.mkdir(hdr.Name); err != nil {
+				return err
+			}
+		case tar.TypeXGlobalHeader:
+			// Archives created by 'git archive' start with a global header
+			// containing the commit, it doesn't describe a file.
+			continue
+		default:
+			log.WithField("entry", hdr.Name).
+				WithField("type", tarEntryType(hdr.Typeflag)).
+				Warn("Skipping archive entry that isn't a regular file or directory")
+		}
+	}
+}
+
+func (e *extractor) extractZip(data []byte) error {
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return fmt.Errorf("failed to read zip archive: %v", err)
+	}
+
+	for _, f := range zr.File {
+		if err := e.count(); err != nil {
+			return err
+		}
+
+		mode := f.Mode()
+
+		switch {
+		case mode.IsRegular():
+			if err := e.writeZipFile(f); err != nil {
+				return err
+			}
+		case mode.IsDir():
+			if err := e.mkdir(f.Name); err != nil {
+				return err
+			}
+		default:
+			log.WithField("entry", f.Name).
+				WithField("type", mode.Type().String()).
+				Warn("Skipping archive entry that isn't a regular file or directory")
+		}
+	}
+
+	return nil
+}
+
+func (e *extractor) writeZipFile(f *zip.File) error {
+	rc, err := f.Open()
+	if err != nil {
+		return fmt.Errorf("failed to open zip entry %q: %v", f.Name, err)
+	}
+
+	defer rc.Close() //nolint:errcheck
+
+	//nolint:gosec
+	return e.writeFile(f.Name, f.Mode(), int64(f.UncompressedSize64), rc)
+}
+
+// count counts an entry and fails if there are too many entries.
+func (e *extractor) count() error {
+	e.entries++
+
+	if e.limits.Entries > 0 && e.entries > e.limits.Entries {
+		return fmt.Errorf("archive has more than %d entries", e.limits.Entries)
+	}
+
+	return nil
+}
+
+// writeFile writes the content of an entry with a declared 'size'.
+func (e *extractor) writeFile(name string, mode os.FileMode, size int64, r io.Reader) error {
+	cleaned, err := entryName(name)
+	if err != nil {
+		return err
+	}
+
+	if e.limits.FileSize > 0 && size > e.limits.FileSize {
+		return fmt.Errorf("archive entry %q is larger than %d bytes", name, e.limits.FileSize)
+	}
+
+	e.totalSize += size
+	if e.limits.TotalSize > 0 && e.totalSize > e.limits.TotalSize {
+		return fmt.Errorf("archive is larger than %d bytes", e.limits.TotalSize)
+	}
+
+	// Entries may be larger than declared, at most the declared size is read.
+	buf, err := ioutil.ReadAll(io.LimitReader(r, size))
+	if err != nil {
+		return fmt.Errorf("failed to extract archive entry: %v", err)
+	}
+
+	filename := filepath.Join(e.dir, cleaned)
+
+	// 'WriteFile' won't create directories, let's do this here instead.
+	// 'MkdirAll' won't fail if directories already exists which makes
+	// it safe to call all the time.
+	dir := filepath.Dir(filename)
+	if err := e.fs.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create operator directory %q: %v", dir, err)
+	}
+
+	if err := afero.WriteFile(e.fs, filename, buf, mode.Perm()); err != nil {
+		return fmt.Errorf("failed to write operator file %q: %v", filename, err)
+	}
+
+	return nil
+}
+
+func (e *extractor) mkdir(name string) error {
+	cleaned, err := entryName(name)
+	if err != nil {
+		return err
+	}
+
+	dir := filepath.Join(e.dir, cleaned)
+	if err := e.fs.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create operator directory %q: %v", dir, err)
+	}
+
+	return nil
+}
+
+// entryName returns the cleaned relative name of an archive entry. Names that
+// are absolute or point outside of the archive are rejected.
+func entryName(name string) (string, error) {
+	slashed := filepath.ToSlash(name)
+
+	if path.IsAbs(slashed) || filepath.IsAbs(name) {
+		return "", fmt.Errorf("archive entry %q has an absolute path", name)
+	}
+
+	cleaned := path.Clean(slashed)
+	if cleaned == ".." || strings.HasPrefix(cleaned, "../") {
+		return "", fmt.Errorf("archive entry %q points outside of the archive", name)
+	}
+
+	return filepath.FromSlash(cleaned), nil
+}
+
+// unwrap returns the only top-level directory in 'dir' if there are no other
+// top-level entries, and 'dir' otherwise. Release archives, e.g. of GitHub,
+// wrap their files in such a directory.
+func unwrap(fs afero.Fs, dir string) (string, error) {
+	infos, err := afero.ReadDir(fs, dir)
+	if err != nil {
+		return "", fmt.Errorf("failed to read operator directory: %v", err)
+	}
+
+	if len(infos) != 1 || !infos[0].IsDir() {
+		return dir, nil
+	}
+
+	log.WithField("directory", infos[0].Name()).
+		Debug("Using the only top-level directory of the archive as the operator package")
+
+	return filepath.Join(dir, infos[0].Name()), nil
+}
+
+// findSubdirectory returns the path of 'subdirectory' in the archive extracted
+// to 'dir'. It is looked up at the top level of the archive first, so that
+// archives only containing the subdirectory aren't unwrapped into it, and then
+// in 'unwrapped', the only top-level directory of the archive.
+func findSubdirectory(fs afero.Fs, dir, unwrapped, subdirectory string) (string, error) {
+	relative := filepath.FromSlash(path.Clean("/" + subdirectory))
+
+	for _, base := range []string{dir, unwrapped} {
+		candidate := filepath.Join(base, relative)
+
+		exists, err := afero.DirExists(fs, candidate)
+		if err != nil {
+			return "", err
+		}
+
+		if exists {
+			return candidate, nil
+		}
+	}
+
+	return "", fmt.Errorf("subdirectory %q not found in archive", subdirectory)
+}
+
+// tarEntryType describes the type of a tar entry for logging.
+func tarEntryType(flag byte) string {
+	switch flag {
+	case tar.TypeSymlink:
+		return "symbolic link"
+	case tar.TypeLink:
+		return "hard link"
+	case tar.TypeChar, tar.TypeBlock:
+		return "device"
+	case tar.TypeFifo:
+		return "named pipe"
+	default:
+		return fmt.Sprintf("type %q", flag)
+	}
+}
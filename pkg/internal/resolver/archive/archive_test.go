@@ -0,0 +1,248 @@
+package archive
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"testing"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+)
+
+type entry struct {
+	name     string
+	typeflag byte
+	content  string
+}
+
+func createTarball(t *testing.T, entries []entry) []byte {
+	var buf bytes.Buffer
+
+	gzw := gzip.NewWriter(&buf)
+
+	_, err := gzw.Write(createTar(t, entries))
+	assert.NoError(t, err)
+	assert.NoError(t, gzw.Close())
+
+	return buf.Bytes()
+}
+
+func createTar(t *testing.T, entries []entry) []byte {
+	var buf bytes.Buffer
+
+	tw := tar.NewWriter(&buf)
+
+	for _, e := range entries {
+		typeflag := e.typeflag
+		if typeflag == 0 {
+			typeflag = tar.TypeReg
+		}
+
+		hdr := &tar.Header{Name: e.name, Typeflag: typeflag, Mode: 0644, Size: int64(len(e.content))}
+
+		switch typeflag {
+		case tar.TypeSymlink:
+			hdr.Linkname = "operator.yaml"
+		case tar.TypeXGlobalHeader:
+			hdr = &tar.Header{Typeflag: typeflag, PAXRecords: map[string]string{"comment": "0123456789abcdef"}}
+		}
+
+		assert.NoError(t, tw.WriteHeader(hdr))
+
+		if hdr.Size > 0 {
+			_, err := tw.Write([]byte(e.content))
+			assert.NoError(t, err)
+		}
+	}
+
+	assert.NoError(t, tw.Close())
+
+	return buf.Bytes()
+}
+
+func TestExtract(t *testing.T) {
+	limits := Limits{TotalSize: 100, Entries: 5, FileSize: 20}
+
+	tests := []struct {
+		name         string
+		entries      []entry
+		subdirectory string
+		files        map[string]string
+		expectErr    string
+	}{
+		{
+			name: "package",
+			entries: []entry{
+				{name: "operator.yaml", content: "name: foo"},
+				{name: "templates/", typeflag: tar.TypeDir},
+				{name: "templates/deployment.yaml", content: "kind: Deployment"},
+				{name: "link.yaml", typeflag: tar.TypeSymlink},
+			},
+			files: map[string]string{
+				"operator.yaml":             "name: foo",
+				"templates/deployment.yaml": "kind: Deployment",
+			},
+		},
+		{
+			name: "package wrapped in a directory",
+			entries: []entry{
+				{typeflag: tar.TypeXGlobalHeader},
+				{name: "foo-1.0.0/", typeflag: tar.TypeDir},
+				{name: "foo-1.0.0/operator.yaml", content: "name: foo"},
+				{name: "foo-1.0.0/templates/deployment.yaml", content: "kind: Deployment"},
+			},
+			files: map[string]string{
+				"operator.yaml":             "name: foo",
+				"templates/deployment.yaml": "kind: Deployment",
+			},
+		},
+		{
+			// The only top-level directory isn't unwrapped if it's the
+			// subdirectory.
+			name:         "subdirectory at the top level",
+			entries:      []entry{{name: "operator/operator.yaml", content: "name: foo"}},
+			subdirectory: "operator",
+			files:        map[string]string{"operator.yaml": "name: foo"},
+		},
+		{
+			name: "subdirectory in the wrapping directory",
+			entries: []entry{
+				{name: "repo-1.0.0/README.md", content: "repo"},
+				{name: "repo-1.0.0/operator/operator.yaml", content: "name: foo"},
+			},
+			subdirectory: "operator",
+			files:        map[string]string{"operator.yaml": "name: foo"},
+		},
+		{
+			name:         "missing subdirectory",
+			entries:      []entry{{name: "operator/operator.yaml", content: "name: foo"}},
+			subdirectory: "templates",
+			expectErr:    `subdirectory "templates" not found in archive`,
+		},
+		{
+			name:      "path traversal",
+			entries:   []entry{{name: "templates/../../operator.yaml", content: "name: foo"}},
+			expectErr: `archive entry "templates/../../operator.yaml" points outside of the archive`,
+		},
+		{
+			name:      "absolute path",
+			entries:   []entry{{name: "/etc/operator.yaml", content: "name: foo"}},
+			expectErr: `archive entry "/etc/operator.yaml" has an absolute path`,
+		},
+		{
+			name: "too many entries",
+			entries: []entry{
+				{name: "1.yaml"}, {name: "2.yaml"}, {name: "3.yaml"}, {name: "4.yaml"}, {name: "5.yaml"}, {name: "6.yaml"},
+			},
+			expectErr: "archive has more than 5 entries",
+		},
+		{
+			name:      "file too large",
+			entries:   []entry{{name: "operator.yaml", content: "name: foo-with-a-very-long-name"}},
+			expectErr: `archive entry "operator.yaml" is larger than 20 bytes`,
+		},
+		{
+			name: "tarball at the limits",
+			entries: []entry{
+				{name: "1.yaml", content: "01234567890123456789"},
+				{name: "2.yaml", content: "01234567890123456789"},
+				{name: "3.yaml", content: "01234567890123456789"},
+				{name: "4.yaml", content: "01234567890123456789"},
+				{name: "5.yaml", content: "01234567890123456789"},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+
+		t.Run(test.name, func(t *testing.T) {
+			fs, err := Extract(createTarball(t, test.entries), test.subdirectory, limits)
+
+			if test.expectErr != "" {
+				assert.EqualError(t, err, test.expectErr)
+				return
+			}
+
+			assert.NoError(t, err)
+
+			for name, content := range test.files {
+				actual, err := afero.ReadFile(fs, name)
+				assert.NoError(t, err, name)
+				assert.Equal(t, content, string(actual), name)
+			}
+
+			exists, err := afero.Exists(fs, "link.yaml")
+			assert.NoError(t, err)
+			assert.False(t, exists)
+		})
+	}
+
+	tarball := createTarball(t, []entry{
+		{name: "1.yaml", content: "01234567890123456789"},
+		{name: "2.yaml", content: "01234567890123456789"},
+	})
+
+	_, err := Extract(tarball, "", Limits{TotalSize: 30})
+	assert.EqualError(t, err, "archive is larger than 30 bytes")
+
+	_, err = Extract(tarball, "", Limits{})
+	assert.NoError(t, err)
+}
+
+func TestExtractFormats(t *testing.T) {
+	entries := []entry{
+		{name: "foo-1.0.0/operator.yaml", content: "name: foo"},
+		{name: "foo-1.0.0/templates/deployment.yaml", content: "kind: Deployment"},
+	}
+
+	tarball := createTar(t, entries)
+
+	var zstdBuf bytes.Buffer
+
+	zw, err := zstd.NewWriter(&zstdBuf)
+	assert.NoError(t, err)
+
+	_, err = zw.Write(tarball)
+	assert.NoError(t, err)
+	assert.NoError(t, zw.Close())
+
+	var zipBuf bytes.Buffer
+
+	archive := zip.NewWriter(&zipBuf)
+
+	_, err = archive.Create("foo-1.0.0/")
+	assert.NoError(t, err)
+
+	for _, e := range entries {
+		w, err := archive.Create(e.name)
+		assert.NoError(t, err)
+
+		_, err = w.Write([]byte(e.content))
+		assert.NoError(t, err)
+	}
+
+	assert.NoError(t, archive.Close())
+
+	formats := map[string][]byte{
+		"tar":     tarball,
+		"tar.gz":  createTarball(t, entries),
+		"tar.zst": zstdBuf.Bytes(),
+		"zip":     zipBuf.Bytes(),
+	}
+
+	for format, data := range formats {
+		fs, err := Extract(data, "", DefaultLimits())
+		assert.NoError(t, err, format)
+
+		content, err := afero.ReadFile(fs, "templates/deployment.yaml")
+		assert.NoError(t, err, format)
+		assert.Equal(t, "kind: Deployment", string(content), format)
+	}
+
+	_, err = Extract([]byte("<html>Not Found</html>"), "", DefaultLimits())
+	assert.EqualError(t, err, "unknown archive format, expected a tar, tar.gz, tar.zst or zip archive")
+}
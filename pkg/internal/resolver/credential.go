@@ -0,0 +1,154 @@
+package resolver
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	neturl "net/url"
+	"os"
+	"strings"
+
+	"github.com/spf13/afero"
+
+	o "github.com/kudobuilder/kitt/pkg/internal/apis/operator"
+	"github.com/kudobuilder/kitt/pkg/internal/config"
+	"github.com/kudobuilder/kitt/pkg/internal/oci"
+	"github.com/kudobuilder/kitt/pkg/internal/resolver/git"
+	"github.com/kudobuilder/kitt/pkg/internal/resolver/url"
+)
+
+// readCredential returns the secret referenced by 'credential' from an
+// environment variable or a file.
+func readCredential(fs afero.Fs, credential o.Credential) (string, error) {
+	switch {
+	case credential.Env != "" && credential.File != "":
+		return "", errors.New("credential must reference either an environment variable or a file, not both")
+	case credential.Env != "":
+		value, ok := os.LookupEnv(credential.Env)
+		if !ok {
+			return "", fmt.Errorf("environment variable %q isn't set", credential.Env)
+		}
+
+		return value, nil
+	case credential.File != "":
+		content, err := afero.ReadFile(fs, credential.File)
+		if err != nil {
+			return "", fmt.Errorf("failed to read credential file: %v", err)
+		}
+
+		return strings.TrimRight(string(content), "\r\n"), nil
+	default:
+		return "", errors.New("credential must reference an environment variable or a file")
+	}
+}
+
+// gitAuth reads the credentials referenced by 'auth' of a Git source.
+func gitAuth(fs afero.Fs, auth *o.GitAuth) (git.Auth, error) {
+	if auth == nil {
+		return git.Auth{}, nil
+	}
+
+	result := git.Auth{
+		Username: auth.Username,
+		SSHKey:   auth.SSHKey,
+	}
+
+	if auth.Password != nil {
+		if auth.Username == "" {
+			return git.Auth{}, errors.New("a username is required if a password is set")
+		}
+
+		password, err := readCredential(fs, *auth.Password)
+		if err != nil {
+			return git.Auth{}, fmt.Errorf("failed to read password: %v", err)
+		}
+
+		result.Password = password
+	}
+
+	return result, nil
+}
+
+// urlAuth reads the credentials and headers of requests for 'rawURL'. They are
+// referenced by 'auth' if set, or otherwise by the host of 'rawURL' in 'cfg'.
+func urlAuth(fs afero.Fs, rawURL string, auth *o.URLAuth, cfg config.Config) (url.Auth, error) {
+	if auth == nil {
+		u, err := neturl.Parse(rawURL)
+		if err != nil {
+			return url.Auth{}, err
+		}
+
+		auth = cfg.Auth(u.Host, u.Hostname())
+		if auth == nil {
+			return url.Auth{}, nil
+		}
+	}
+
+	result := url.Auth{
+		Username: auth.Username,
+		Header:   http.Header{},
+	}
+
+	if auth.Password != nil {
+		password, err := readCredential(fs, *auth.Password)
+		if err != nil {
+			return url.Auth{}, fmt.Errorf("failed to read password: %v", err)
+		}
+
+		result.Password = password
+	}
+
+	if auth.BearerToken != nil {
+		token, err := readCredential(fs, *auth.BearerToken)
+		if err != nil {
+			return url.Auth{}, fmt.Errorf("failed to read bearer token: %v", err)
+		}
+
+		result.BearerToken = token
+	}
+
+	for _, header := range auth.Headers {
+		switch {
+		case header.Name == "":
+			return url.Auth{}, errors.New("header without a name")
+		case header.ValueFrom != nil && header.Value != "":
+			return url.Auth{}, fmt.Errorf("header %q must set either a value or a reference to it, not both", header.Name)
+		case header.ValueFrom != nil:
+			value, err := readCredential(fs, *header.ValueFrom)
+			if err != nil {
+				return url.Auth{}, fmt.Errorf("failed to read header %q: %v", header.Name, err)
+			}
+
+			result.Header.Add(header.Name, value)
+		default:
+			result.Header.Add(header.Name, header.Value)
+		}
+	}
+
+	return result, nil
+}
+
+// registryCredentials returns the credentials of OCI registries. Registries
+// configured as hosts in 'cfg' use their username and password, other
+// registries the credentials of 'docker'.
+func registryCredentials(fs afero.Fs, cfg config.Config, docker map[string]oci.Credentials) oci.CredentialsFunc {
+	return func(registry string) (*oci.Credentials, error) {
+		auth, err := urlAuth(fs, "https://"+registry, nil, cfg)
+		if err != nil {
+			return nil, fmt.Errorf("invalid auth of registry %q: %v", registry, err)
+		}
+
+		switch {
+		case auth.BearerToken != "" || len(auth.Header) > 0:
+			return nil, fmt.Errorf("auth of registry %q can only set a username and password", registry)
+		case auth.Username != "":
+			return &oci.Credentials{Username: auth.Username, Password: auth.Password}, nil
+		}
+
+		if credentials, ok := docker[registry]; ok {
+			return &credentials, nil
+		}
+
+		return nil, nil
+	}
+}
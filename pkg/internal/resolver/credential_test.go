@@ -0,0 +1,183 @@
+package resolver
+
+import (
+	"net/http"
+	"os"
+	"testing"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+
+	o "github.com/kudobuilder/kitt/pkg/internal/apis/operator"
+	"github.com/kudobuilder/kitt/pkg/internal/config"
+	"github.com/kudobuilder/kitt/pkg/internal/oci"
+	"github.com/kudobuilder/kitt/pkg/internal/resolver/git"
+	"github.com/kudobuilder/kitt/pkg/internal/resolver/url"
+)
+
+func TestReadCredential(t *testing.T) {
+	fs := afero.NewMemMapFs()
+	assert.NoError(t, afero.WriteFile(fs, "/token", []byte("file-secret\n"), 0600))
+
+	assert.NoError(t, os.Setenv("KITT_TEST_TOKEN", "env-secret"))
+
+	defer os.Unsetenv("KITT_TEST_TOKEN")
+
+	tests := []struct {
+		name       string
+		credential o.Credential
+		expected   string
+		expectErr  string
+	}{
+		{
+			name:       "environment variable",
+			credential: o.Credential{Env: "KITT_TEST_TOKEN"},
+			expected:   "env-secret",
+		},
+		{
+			name:       "file",
+			credential: o.Credential{File: "/token"},
+			expected:   "file-secret",
+		},
+		{
+			name:       "unset environment variable",
+			credential: o.Credential{Env: "KITT_TEST_UNSET"},
+			expectErr:  `environment variable "KITT_TEST_UNSET" isn't set`,
+		},
+		{
+			name:       "missing file",
+			credential: o.Credential{File: "/missing"},
+			expectErr:  "failed to read credential file: open /missing: file does not exist",
+		},
+		{
+			name:       "both",
+			credential: o.Credential{Env: "KITT_TEST_TOKEN", File: "/token"},
+			expectErr:  "credential must reference either an environment variable or a file, not both",
+		},
+		{
+			name:      "none",
+			expectErr: "credential must reference an environment variable or a file",
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+
+		t.Run(test.name, func(t *testing.T) {
+			value, err := readCredential(fs, test.credential)
+
+			if test.expectErr != "" {
+				assert.EqualError(t, err, test.expectErr)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, test.expected, value)
+		})
+	}
+}
+
+func TestGitAuth(t *testing.T) {
+	fs := afero.NewMemMapFs()
+	assert.NoError(t, afero.WriteFile(fs, "/token", []byte("secret"), 0600))
+
+	auth, err := gitAuth(fs, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, git.Auth{}, auth)
+
+	auth, err = gitAuth(fs, &o.GitAuth{
+		Username: "kitt",
+		Password: &o.Credential{File: "/token"},
+		SSHKey:   "/id_rsa",
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, git.Auth{Username: "kitt", Password: "secret", SSHKey: "/id_rsa"}, auth)
+
+	_, err = gitAuth(fs, &o.GitAuth{Password: &o.Credential{File: "/token"}})
+	assert.EqualError(t, err, "a username is required if a password is set")
+}
+
+func TestURLAuth(t *testing.T) {
+	fs := afero.NewMemMapFs()
+	assert.NoError(t, afero.WriteFile(fs, "/token", []byte("secret"), 0600))
+
+	cfg := config.Config{
+		Hosts: []config.Host{
+			{
+				Host: "example.org",
+				Auth: o.URLAuth{
+					BearerToken: &o.Credential{File: "/token"},
+					Headers: []o.Header{
+						{Name: "Accept", Value: "application/octet-stream"},
+						{Name: "X-Api-Key", ValueFrom: &o.Credential{File: "/token"}},
+					},
+				},
+			},
+		},
+	}
+
+	auth, err := urlAuth(fs, "https://example.org/foo.tgz", nil, cfg)
+	assert.NoError(t, err)
+	assert.Equal(t, url.Auth{
+		BearerToken: "secret",
+		Header:      http.Header{"Accept": {"application/octet-stream"}, "X-Api-Key": {"secret"}},
+	}, auth)
+
+	// Auth of a version takes precedence over the config.
+	auth, err = urlAuth(fs, "https://example.org/foo.tgz", &o.URLAuth{
+		Username: "kitt",
+		Password: &o.Credential{File: "/token"},
+	}, cfg)
+	assert.NoError(t, err)
+	assert.Equal(t, url.Auth{Username: "kitt", Password: "secret", Header: http.Header{}}, auth)
+
+	auth, err = urlAuth(fs, "https://example.com/foo.tgz", nil, cfg)
+	assert.NoError(t, err)
+	assert.Equal(t, url.Auth{}, auth)
+
+	_, err = urlAuth(fs, "https://example.org/foo.tgz", &o.URLAuth{
+		Headers: []o.Header{{Name: "X-Api-Key", Value: "key", ValueFrom: &o.Credential{File: "/token"}}},
+	}, cfg)
+	assert.EqualError(t, err, `header "X-Api-Key" must set either a value or a reference to it, not both`)
+}
+
+func TestRegistryCredentials(t *testing.T) {
+	fs := afero.NewMemMapFs()
+	assert.NoError(t, afero.WriteFile(fs, "/token", []byte("secret"), 0600))
+
+	cfg := config.Config{
+		Hosts: []config.Host{
+			{
+				Host: "registry.example.org",
+				Auth: o.URLAuth{Username: "kitt", Password: &o.Credential{File: "/token"}},
+			},
+			{
+				Host: "example.org",
+				Auth: o.URLAuth{BearerToken: &o.Credential{File: "/token"}},
+			},
+		},
+	}
+
+	docker := map[string]oci.Credentials{
+		"registry.example.org": {Username: "docker", Password: "docker-secret"},
+		"docker.example.org":   {Username: "docker", Password: "docker-secret"},
+	}
+
+	credentials := registryCredentials(fs, cfg, docker)
+
+	// The config takes precedence over the Docker credentials.
+	c, err := credentials("registry.example.org:5000")
+	assert.NoError(t, err)
+	assert.Equal(t, &oci.Credentials{Username: "kitt", Password: "secret"}, c)
+
+	c, err = credentials("docker.example.org")
+	assert.NoError(t, err)
+	assert.Equal(t, &oci.Credentials{Username: "docker", Password: "docker-secret"}, c)
+
+	c, err = credentials("example.com")
+	assert.NoError(t, err)
+	assert.Nil(t, c)
+
+	_, err = credentials("example.org")
+	assert.EqualError(t, err, `auth of registry "example.org" can only set a username and password`)
+}
@@ -0,0 +1,89 @@
+package git
+
+import (
+	"context"
+	"fmt"
+)
+
+// Names of the available Git backends.
+const (
+	// BackendExec runs the 'git' binary.
+	BackendExec = "exec"
+
+	// BackendGo uses a Git implementation in Go and doesn't need a 'git'
+	// binary.
+	BackendGo = "go"
+)
+
+// Backend runs the Git operations of a 'Cache' on bare repositories.
+// Calls for the same repository are serialized by the cache, except for
+// 'Checkout', which only reads commits from the repository and may run
+// concurrently with other calls.
+type Backend interface {
+	// Init creates a bare repository in 'repoDir', or prepares an existing
+	// one for reuse.
+	Init(ctx context.Context, repoDir string) error
+
+	// Fetch fetches a branch or tag 'ref', or a commit 'sha' from 'url' into
+	// the repository and returns the SHA of the commit. The remote repository
+	// is accessed with the credentials of 'auth'.
+	Fetch(ctx context.Context, repoDir, url string, auth Auth, ref, sha string) (string, error)
+
+	// Tags returns the names of the tags of the remote repository at 'url'.
+	// The remote repository is accessed with the credentials of 'auth'.
+	Tags(ctx context.Context, repoDir, url string, auth Auth) ([]string, error)
+
+	// Submodules returns the submodules of a fetched commit with the URLs
+	// listed in its '.gitmodules' file. Contents missing from the repository
+	// are fetched from 'url' with the credentials of 'auth'.
+	Submodules(ctx context.Context, repoDir, url string, auth Auth, commit string) ([]Submodule, error)
+
+	// FetchLFS fetches the Git LFS objects of the files in 'directory' of
+	// a fetched commit from 'url' into the repository. The remote repository
+	// is accessed with the credentials of 'auth'.
+	FetchLFS(ctx context.Context, repoDir, url string, auth Auth, commit, directory string) error
+
+	// Checkout writes the files of 'directory' of a fetched commit into
+	// 'worktreeDir'. Files outside of 'directory' aren't written. If
+	// 'directory' is empty, all files of the commit are written.
+	// If 'lfs' is true, Git LFS pointers are replaced by the content of
+	// their fetched objects. Contents missing from the repository are fetched
+	// from 'url' with the credentials of 'auth'.
+	Checkout(ctx context.Context, repoDir, worktreeDir, url string, auth Auth, commit, directory string, lfs bool) error
+}
+
+// Auth holds the credentials of a remote repository. Empty fields aren't used,
+// the backends then fall back to the credentials of the host.
+type Auth struct {
+	// Username and Password for HTTP basic authentication.
+	Username string
+	Password string
+
+	// SSHKey is the path of a private key for SSH URLs.
+	SSHKey string
+}
+
+// Submodule is a submodule of a commit.
+type Submodule struct {
+	// Path of the submodule in the commit.
+	Path string
+
+	// URL of the submodule's repository. Relative URLs are relative to the
+	// URL of the repository of the commit.
+	URL string
+
+	// Commit of the submodule's repository the commit references.
+	Commit string
+}
+
+// NewBackend returns the backend with the given name.
+func NewBackend(name string) (Backend, error) {
+	switch name {
+	case BackendExec, "":
+		return newExecBackend(), nil
+	case BackendGo:
+		return goBackend{}, nil
+	default:
+		return nil, fmt.Errorf("unknown Git backend %q, must be %q or %q", name, BackendExec, BackendGo)
+	}
+}
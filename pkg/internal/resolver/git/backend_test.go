@@ -0,0 +1,336 @@
+package git
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http/cgi"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBackends(t *testing.T) {
+	// The file transport of go-git runs 'git-upload-pack'.
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git isn't installed")
+	}
+
+	sourceDir := t.TempDir()
+
+	source, err := git.PlainInit(sourceDir, false)
+	assert.NoError(t, err)
+
+	worktree, err := source.Worktree()
+	assert.NoError(t, err)
+
+	assert.NoError(t, os.Mkdir(filepath.Join(sourceDir, "operator"), 0755))
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(sourceDir, "README.md"), []byte("kitt"), 0644))
+
+	_, err = worktree.Add("README.md")
+	assert.NoError(t, err)
+
+	signature := &object.Signature{Name: "kitt", Email: "kitt@example.org", When: time.Now()}
+
+	commit := func(content string) plumbing.Hash {
+		assert.NoError(t, ioutil.WriteFile(filepath.Join(sourceDir, "operator", "operator.yaml"), []byte(content), 0644))
+
+		_, err := worktree.Add("operator/operator.yaml")
+		assert.NoError(t, err)
+
+		hash, err := worktree.Commit(content, &git.CommitOptions{Author: signature})
+		assert.NoError(t, err)
+
+		return hash
+	}
+
+	v1 := commit("version: 1")
+
+	_, err = source.CreateTag("v1", v1, &git.CreateTagOptions{Tagger: signature, Message: "v1"})
+	assert.NoError(t, err)
+
+	v2 := commit("version: 2")
+
+	ctx := context.Background()
+
+	tests := []struct {
+		name     string
+		ref      string
+		sha      string
+		expected plumbing.Hash
+		content  string
+	}{
+		{name: "annotated tag", ref: "v1", expected: v1, content: "version: 1"},
+		{name: "branch", ref: "master", expected: v2, content: "version: 2"},
+		{name: "full tag reference", ref: TagRef("v1"), expected: v1, content: "version: 1"},
+		{name: "full branch reference", ref: BranchRef("master"), expected: v2, content: "version: 2"},
+		{name: "SHA", sha: v1.String(), expected: v1, content: "version: 1"},
+		{name: "abbreviated SHA", sha: v2.String()[:7], expected: v2, content: "version: 2"},
+	}
+
+	for _, name := range []string{BackendExec, BackendGo} {
+		backend, err := NewBackend(name)
+		assert.NoError(t, err)
+
+		repoDir := filepath.Join(t.TempDir(), "repo")
+
+		assert.NoError(t, backend.Init(ctx, repoDir), name)
+
+		for _, test := range tests {
+			fetched, err := backend.Fetch(ctx, repoDir, sourceDir, Auth{}, test.ref, test.sha)
+			assert.NoError(t, err, name, test.name)
+			assert.Equal(t, test.expected.String(), fetched, name, test.name)
+
+			worktreeDir := t.TempDir()
+
+			err = backend.Checkout(ctx, repoDir, worktreeDir, sourceDir, Auth{}, fetched, "operator", false)
+			assert.NoError(t, err, name, test.name)
+
+			content, err := ioutil.ReadFile(filepath.Join(worktreeDir, "operator", "operator.yaml"))
+			assert.NoError(t, err, name, test.name)
+			assert.Equal(t, test.content, string(content), name, test.name)
+
+			// Files outside of the operator directory aren't checked out.
+			assert.NoFileExists(t, filepath.Join(worktreeDir, "README.md"), name, test.name)
+		}
+
+		_, err = backend.Fetch(ctx, repoDir, sourceDir, Auth{}, "v3", "")
+		assert.Error(t, err, name)
+
+		tags, err := backend.Tags(ctx, repoDir, sourceDir, Auth{})
+		assert.NoError(t, err, name)
+		assert.Equal(t, []string{"v1"}, tags, name)
+	}
+}
+
+func TestBackendsSubmodules(t *testing.T) {
+	gitPath, err := exec.LookPath("git")
+	if err != nil {
+		t.Skip("git isn't installed")
+	}
+
+	dir := t.TempDir()
+
+	// The templates are in a submodule of the operator directory, which has
+	// a submodule itself.
+	gitInit(t, filepath.Join(dir, "nested"), map[string]string{"nested.yaml": "nested"})
+	gitInit(t, filepath.Join(dir, "templates"), map[string]string{"deployment.yaml": "deployment"})
+	runGit(t, filepath.Join(dir, "templates"), "submodule", "add", "../nested", "nested")
+	runGit(t, filepath.Join(dir, "templates"), "commit", "-m", "nested")
+	gitInit(t, filepath.Join(dir, "source"), map[string]string{"operator/operator.yaml": "operator"})
+	runGit(t, filepath.Join(dir, "source"), "submodule", "add", "../templates", "operator/templates")
+	runGit(t, filepath.Join(dir, "source"), "commit", "-m", "templates")
+
+	// Local submodule URLs are rejected, the repositories are served over
+	// HTTP instead.
+	server := httptest.NewServer(&cgi.Handler{
+		Path: gitPath,
+		Args: []string{"http-backend"},
+		Env:  []string{"GIT_PROJECT_ROOT=" + dir, "GIT_HTTP_EXPORT_ALL=1"},
+	})
+	defer server.Close()
+
+	sourceURL := server.URL + "/source"
+
+	ctx := context.Background()
+
+	for _, name := range []string{BackendExec, BackendGo} {
+		backend, err := NewBackend(name)
+		assert.NoError(t, err)
+
+		cache, err := NewCache("", backend)
+		assert.NoError(t, err)
+
+		options := CheckoutOptions{Submodules: true}
+
+		worktreeDir := t.TempDir()
+
+		_, err = cache.Checkout(ctx, worktreeDir, sourceURL, Auth{}, BranchRef("main"), "", "operator", options)
+		assert.NoError(t, err, name)
+
+		for file, content := range map[string]string{
+			"operator/operator.yaml":                "operator",
+			"operator/templates/deployment.yaml":    "deployment",
+			"operator/templates/nested/nested.yaml": "nested",
+		} {
+			actual, err := ioutil.ReadFile(filepath.Join(worktreeDir, file))
+			assert.NoError(t, err, name, file)
+			assert.Equal(t, content, string(actual), name, file)
+		}
+
+		// A directory within a submodule is only checked out from the
+		// submodule.
+		worktreeDir = t.TempDir()
+
+		_, err = cache.Checkout(ctx, worktreeDir, sourceURL, Auth{}, BranchRef("main"), "",
+			"operator/templates/nested", options)
+		assert.NoError(t, err, name)
+
+		assert.FileExists(t, filepath.Join(worktreeDir, "operator", "templates", "nested", "nested.yaml"), name)
+		assert.NoFileExists(t, filepath.Join(worktreeDir, "operator", "operator.yaml"), name)
+		assert.NoFileExists(t, filepath.Join(worktreeDir, "operator", "templates", "deployment.yaml"), name)
+
+		assert.NoError(t, cache.Remove(), name)
+	}
+}
+
+func TestBackendsLFS(t *testing.T) {
+	if _, err := exec.LookPath("git-lfs"); err != nil {
+		t.Skip("git-lfs isn't installed")
+	}
+
+	sourceDir := filepath.Join(t.TempDir(), "source")
+
+	gitInit(t, sourceDir, map[string]string{"README.md": "kitt"})
+	runGit(t, sourceDir, "lfs", "install", "--local")
+	runGit(t, sourceDir, "lfs", "track", "*.bin")
+	assert.NoError(t, os.MkdirAll(filepath.Join(sourceDir, "operator"), 0755))
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(sourceDir, "operator", "crds.bin"), []byte("crds"), 0644))
+	runGit(t, sourceDir, "add", ".")
+	runGit(t, sourceDir, "commit", "-m", "crds")
+
+	// Pointer files aren't fetched into partial clones until they are needed.
+	runGit(t, sourceDir, "config", "uploadpack.allowFilter", "true")
+
+	ctx := context.Background()
+
+	tests := []struct {
+		name    string
+		options CheckoutOptions
+		content string
+	}{
+		{name: "LFS", options: CheckoutOptions{LFS: true}, content: "crds"},
+		{name: "pointer", options: CheckoutOptions{}, content: lfsPointerPrefix},
+	}
+
+	backend, err := NewBackend(BackendExec)
+	assert.NoError(t, err)
+
+	cache, err := NewCache("", backend)
+	assert.NoError(t, err)
+
+	for _, test := range tests {
+		worktreeDir := t.TempDir()
+
+		_, err := cache.Checkout(ctx, worktreeDir, sourceDir, Auth{}, BranchRef("main"), "", "operator", test.options)
+		assert.NoError(t, err, test.name)
+
+		content, err := ioutil.ReadFile(filepath.Join(worktreeDir, "operator", "crds.bin"))
+		assert.NoError(t, err, test.name)
+		assert.True(t, strings.HasPrefix(string(content), test.content), test.name)
+	}
+
+	assert.NoError(t, cache.Remove())
+
+	backend, err = NewBackend(BackendGo)
+	assert.NoError(t, err)
+
+	cache, err = NewCache("", backend)
+	assert.NoError(t, err)
+
+	_, err = cache.Checkout(ctx, t.TempDir(), sourceDir, Auth{}, BranchRef("main"), "", "operator",
+		CheckoutOptions{LFS: true})
+	assert.EqualError(t, err, `the "go" Git backend doesn't support Git LFS, use the "exec" backend`)
+
+	assert.NoError(t, cache.Remove())
+}
+
+func TestExecBackendPartialClone(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git isn't installed")
+	}
+
+	sourceDir := filepath.Join(t.TempDir(), "source")
+
+	gitInit(t, sourceDir, map[string]string{"README.md": "kitt", "operator/operator.yaml": "operator"})
+	runGit(t, sourceDir, "config", "uploadpack.allowFilter", "true")
+
+	ctx := context.Background()
+	backend := newExecBackend()
+	repoDir := filepath.Join(t.TempDir(), "repo")
+
+	assert.NoError(t, backend.Init(ctx, repoDir))
+
+	commit, err := backend.Fetch(ctx, repoDir, sourceDir, Auth{}, BranchRef("main"), "")
+	assert.NoError(t, err)
+
+	worktreeDir := t.TempDir()
+
+	assert.NoError(t, backend.Checkout(ctx, repoDir, worktreeDir, sourceDir, Auth{}, commit, "operator", false))
+
+	content, err := ioutil.ReadFile(filepath.Join(worktreeDir, "operator", "operator.yaml"))
+	assert.NoError(t, err)
+	assert.Equal(t, "operator", string(content))
+
+	// Only the contents of the checked out files have been fetched.
+	missing, err := runAndLog(ctx, log.NewEntry(log.StandardLogger()), "git", "-C", repoDir,
+		"rev-list", "--objects", "--missing=print", commit)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, strings.Count(missing, "?"), missing)
+}
+
+func TestIsUnservedObject(t *testing.T) {
+	assert.True(t, isUnservedObject(errors.New("fatal: remote error: upload-pack: not our ref 0123456789abcdef")))
+	assert.True(t, isUnservedObject(errors.New("error: Server does not allow request for unadvertised object 0123")))
+	assert.False(t, isUnservedObject(errors.New("fatal: Authentication failed for 'https://example.org/'")))
+}
+
+func TestExecCredentialsScope(t *testing.T) {
+	auth := Auth{Username: "kitt", Password: "secret"}
+
+	fill := func(url, host string) (string, error) {
+		cmd := remoteCommand(context.Background(), t.TempDir(), url, auth, "credential", "fill")
+		cmd.Stdin = strings.NewReader("protocol=https\nhost=" + host + "\npath=foo.git\n\n")
+
+		output, err := cmd.Output()
+
+		return string(output), err
+	}
+
+	output, err := fill("https://example.org/foo.git", "example.org")
+	assert.NoError(t, err)
+	assert.Contains(t, output, "password=secret")
+
+	// Credentials aren't provided for other hosts, e.g. of redirects.
+	output, err = fill("https://example.org/foo.git", "example.com")
+	assert.Error(t, err)
+	assert.NotContains(t, output, "secret")
+
+	_, err = fill("https://example.org:8443/foo.git", "example.org")
+	assert.Error(t, err)
+}
+
+// gitInit creates a Git repository in 'dir' with a commit of 'files'.
+func gitInit(t *testing.T, dir string, files map[string]string) {
+	runGit(t, "", "init", "--initial-branch", "main", dir)
+
+	for name, content := range files {
+		assert.NoError(t, os.MkdirAll(filepath.Dir(filepath.Join(dir, name)), 0755))
+		assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0644))
+	}
+
+	runGit(t, dir, "add", ".")
+	runGit(t, dir, "commit", "-m", "init")
+}
+
+// runGit runs a Git command in 'dir'.
+func runGit(t *testing.T, dir string, args ...string) {
+	// Local submodules aren't allowed by default.
+	cmd := exec.Command("git", append([]string{
+		"-c", "user.name=kitt", "-c", "user.email=kitt@example.org", "-c", "protocol.file.allow=always",
+	}, args...)...)
+	cmd.Dir = dir
+
+	output, err := cmd.CombinedOutput()
+	assert.NoError(t, err, string(output))
+}
@@ -0,0 +1,337 @@
+package git
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/afero"
+)
+
+// Cache keeps a bare repository per Git URL and provides checkouts of
+// revisions fetched into these repositories. Git operations are run by
+// a 'Backend'.
+// It is safe for concurrent use.
+type Cache struct {
+	fs afero.Fs
+
+	// Directory of persistent bare repositories. If empty, repositories are
+	// created in temporary directories.
+	dir string
+
+	mu    sync.Mutex
+	repos map[string]*cachedRepo
+
+	backend Backend
+}
+
+type cachedRepo struct {
+	// Serializes all Git operations writing to the repository.
+	mu sync.Mutex
+
+	dir    string
+	opened bool
+	err    error
+}
+
+// NewCache creates a new cache running Git operations with 'backend'.
+// If 'dir' is empty, repositories are created in temporary directories.
+// Callers are responsible for removing these directories by calling 'Remove'
+// once the cache is no longer needed.
+// Otherwise, repositories are kept as bare repositories in 'dir' and are only
+// updated incrementally when they are used again, even by a later run.
+func NewCache(dir string, backend Backend) (*Cache, error) {
+	fs := afero.NewOsFs()
+
+	if dir != "" {
+		if err := fs.MkdirAll(dir, 0755); err != nil {
+			return nil, fmt.Errorf("failed to create cache directory %q: %v", dir, err)
+		}
+	}
+
+	return &Cache{
+		fs:    fs,
+		dir:   dir,
+		repos: map[string]*cachedRepo{},
+
+		backend: backend,
+	}, nil
+}
+
+// CheckoutOptions enable optional parts of a checkout.
+type CheckoutOptions struct {
+	// Submodules checks out the submodules of the checked out directory
+	// recursively. They are only fetched with the credentials of their parent
+	// repository if they are on the same host, and local submodule URLs are
+	// rejected.
+	Submodules bool
+
+	// LFS replaces Git LFS pointers by the content of their objects.
+	LFS bool
+}
+
+// Checkout checks out 'directory' of a reference or SHA of the Git repository
+// at 'url' into 'worktreeDir' and returns the SHA of the checked out commit.
+// The repository is created on first use and the revision is fetched into it
+// with the credentials of 'auth'.
+// If both 'ref' and 'sha' are set, 'sha' has to be a full SHA and the branch
+// or tag has to point to this commit.
+func (c *Cache) Checkout(
+	ctx context.Context,
+	worktreeDir, url string,
+	auth Auth,
+	ref, sha, directory string,
+	options CheckoutOptions,
+) (string, error) {
+	fetched, err := c.prepare(ctx, url, auth, ref, sha, directory, options)
+	if err != nil {
+		return "", err
+	}
+
+	// The directory is only checked out from the submodule containing it.
+	if !fetched.inside {
+		log.WithField("url", url).
+			WithField("commit", fetched.commit).
+			WithField("directory", worktreeDir).
+			WithField("path", directory).
+			Debug("Checking out Git commit")
+
+		// Worktrees of submodules may not have been created by the checkout of
+		// their parent repository.
+		if err := c.fs.MkdirAll(worktreeDir, 0755); err != nil {
+			return "", fmt.Errorf("failed to create worktree directory %q: %v", worktreeDir, err)
+		}
+
+		// Checkouts only read the fetched commit from the repository and write
+		// to their own worktree, they don't need the lock of the repository.
+		err := c.backend.Checkout(ctx, fetched.repoDir, worktreeDir, url, auth, fetched.commit, directory, options.LFS)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	// Submodules are checked out once the lock of the repository has been
+	// released, they may reference the same repository.
+	for _, submodule := range fetched.submodules {
+		log.WithField("url", url).
+			WithField("submodule", submodule.Path).
+			WithField("commit", submodule.Commit).
+			Info("Checking out Git submodule")
+
+		// Credentials are only sent to the host they are meant for, not to
+		// any host a repository names in its submodules.
+		subAuth := Auth{}
+		if sameHost(url, submodule.URL) {
+			subAuth = auth
+		}
+
+		_, err := c.Checkout(ctx, filepath.Join(worktreeDir, submodule.Path), submodule.URL, subAuth,
+			"", submodule.Commit, submodule.directory, options)
+		if err != nil {
+			return "", fmt.Errorf("failed to check out submodule %q of %q: %v", submodule.Path, url, err)
+		}
+	}
+
+	return fetched.commit, nil
+}
+
+// fetchedRevision is a revision fetched into a repository, ready to be
+// checked out.
+type fetchedRevision struct {
+	repoDir string
+	commit  string
+
+	// Submodules which have to be checked out as well.
+	submodules []submoduleCheckout
+
+	// Whether the directory to check out is inside of a submodule and nothing
+	// has to be checked out from the repository itself.
+	inside bool
+}
+
+// prepare fetches a revision and everything needed to check it out into a
+// repository while holding the lock of the repository.
+func (c *Cache) prepare(
+	ctx context.Context,
+	url string,
+	auth Auth,
+	ref, sha, directory string,
+	options CheckoutOptions,
+) (fetchedRevision, error) {
+	// Abbreviated SHAs would accept any commit sharing their prefix.
+	if ref != "" && sha != "" && !fullSHA.MatchString(strings.ToLower(sha)) {
+		return fetchedRevision{}, fmt.Errorf(
+			"SHA %q pinning %q of %q has to be a full SHA of 40 hex characters", sha, ref, url)
+	}
+
+	repo := c.repo(url)
+
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	commit, err := c.fetch(ctx, repo, url, auth, ref, sha)
+	if err != nil {
+		return fetchedRevision{}, err
+	}
+
+	if ref != "" && sha != "" && commit != strings.ToLower(sha) {
+		return fetchedRevision{}, fmt.Errorf(
+			"%q of %q points to commit %q instead of the expected SHA %q", ref, url, commit, sha)
+	}
+
+	fetched := fetchedRevision{repoDir: repo.dir, commit: commit}
+
+	if options.Submodules {
+		all, err := c.backend.Submodules(ctx, repo.dir, url, auth, commit)
+		if err != nil {
+			return fetchedRevision{}, err
+		}
+
+		fetched.submodules, fetched.inside, err = submoduleCheckouts(all, url, directory)
+		if err != nil {
+			return fetchedRevision{}, err
+		}
+
+		if fetched.inside {
+			return fetched, nil
+		}
+	}
+
+	if options.LFS {
+		if err := c.backend.FetchLFS(ctx, repo.dir, url, auth, commit, directory); err != nil {
+			return fetchedRevision{}, err
+		}
+	}
+
+	return fetched, nil
+}
+
+// Commit returns the SHA of the commit a branch or tag of the Git repository
+// at 'url' currently points to. The repository is created on first use and
+// the revision is fetched into it with the credentials of 'auth'.
+func (c *Cache) Commit(ctx context.Context, url string, auth Auth, ref string) (string, error) {
+	repo := c.repo(url)
+
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	return c.fetch(ctx, repo, url, auth, ref, "")
+}
+
+// Tags returns the names of the tags of the Git repository at 'url'. The
+// repository is accessed with the credentials of 'auth'.
+func (c *Cache) Tags(ctx context.Context, url string, auth Auth) ([]string, error) {
+	repo := c.repo(url)
+
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	if err := c.open(ctx, repo, url); err != nil {
+		return nil, err
+	}
+
+	return c.backend.Tags(ctx, repo.dir, url, auth)
+}
+
+// fetch opens a repository if needed and fetches a revision into it.
+// Callers must hold the lock of the repository.
+func (c *Cache) fetch(ctx context.Context, repo *cachedRepo, url string, auth Auth, ref, sha string) (string, error) {
+	if err := c.open(ctx, repo, url); err != nil {
+		return "", err
+	}
+
+	return c.backend.Fetch(ctx, repo.dir, url, auth, ref, sha)
+}
+
+// open opens a repository on first use.
+// Callers must hold the lock of the repository.
+func (c *Cache) open(ctx context.Context, repo *cachedRepo, url string) error {
+	if !repo.opened {
+		repo.opened = true
+		repo.dir, repo.err = c.openRepo(ctx, url)
+	}
+
+	return repo.err
+}
+
+// Remove removes all temporary repositories of the cache.
+// Persistent repositories are kept.
+func (c *Cache) Remove() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.dir != "" {
+		return nil
+	}
+
+	for url, repo := range c.repos {
+		if repo.dir == "" {
+			continue
+		}
+
+		log.WithField("directory", repo.dir).
+			Debug("Removing cached Git repository")
+
+		if err := c.fs.RemoveAll(repo.dir); err != nil {
+			return err
+		}
+
+		delete(c.repos, url)
+	}
+
+	return nil
+}
+
+func (c *Cache) repo(url string) *cachedRepo {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	repo, ok := c.repos[url]
+	if !ok {
+		repo = &cachedRepo{}
+		c.repos[url] = repo
+	}
+
+	return repo
+}
+
+func (c *Cache) openRepo(ctx context.Context, url string) (string, error) {
+	repoDir := ""
+
+	if c.dir == "" {
+		tempDir, err := afero.TempDir(c.fs, "", "")
+		if err != nil {
+			return "", err
+		}
+
+		repoDir = tempDir
+	} else {
+		sum := sha256.Sum256([]byte(url))
+		repoDir = filepath.Join(c.dir, hex.EncodeToString(sum[:]))
+	}
+
+	log.WithField("url", url).
+		WithField("directory", repoDir).
+		Info("Opening Git repository")
+
+	if err := c.backend.Init(ctx, repoDir); err != nil {
+		// Only temporary repositories are removed, persistent repositories
+		// may still be usable by later runs.
+		if c.dir == "" {
+			if rerr := c.fs.RemoveAll(repoDir); rerr != nil {
+				log.WithField("directory", repoDir).
+					WithError(rerr).
+					Warn("Failed to remove repository directory")
+			}
+		}
+
+		return "", err
+	}
+
+	return repoDir, nil
+}
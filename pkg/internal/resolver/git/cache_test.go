@@ -0,0 +1,217 @@
+package git
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeBackend records the operations of a cache.
+type fakeBackend struct {
+	inits     map[string]int
+	fetches   map[string]int
+	worktrees map[string]string
+
+	// Commits of references, if set.
+	commits map[string]string
+
+	// Submodules of commits, if set.
+	submodules map[string][]Submodule
+
+	// Credentials each URL has been fetched with.
+	auths map[string]Auth
+}
+
+func newFakeBackend() *fakeBackend {
+	return &fakeBackend{
+		inits:     map[string]int{},
+		fetches:   map[string]int{},
+		worktrees: map[string]string{},
+		auths:     map[string]Auth{},
+	}
+}
+
+func (b *fakeBackend) Init(ctx context.Context, repoDir string) error {
+	b.inits[repoDir]++
+	return nil
+}
+
+func (b *fakeBackend) Fetch(ctx context.Context, repoDir, url string, auth Auth, ref, sha string) (string, error) {
+	b.fetches[url]++
+	b.auths[url] = auth
+
+	if commit, ok := b.commits[ref]; ok {
+		return commit, nil
+	}
+
+	return fmt.Sprintf("%s@%s%s", url, ref, sha), nil
+}
+
+func (b *fakeBackend) Tags(ctx context.Context, repoDir, url string, auth Auth) ([]string, error) {
+	return nil, nil
+}
+
+func (b *fakeBackend) Submodules(ctx context.Context, repoDir, url string, auth Auth, commit string) ([]Submodule, error) {
+	return b.submodules[commit], nil
+}
+
+func (b *fakeBackend) FetchLFS(ctx context.Context, repoDir, url string, auth Auth, commit, directory string) error {
+	return nil
+}
+
+func (b *fakeBackend) Checkout(
+	ctx context.Context,
+	repoDir, worktreeDir, url string,
+	auth Auth,
+	commit, directory string,
+	lfs bool,
+) error {
+	b.worktrees[worktreeDir] = commit + ":" + directory
+	return nil
+}
+
+func TestCacheCheckout(t *testing.T) {
+	backend := newFakeBackend()
+
+	cache, err := NewCache("", backend)
+	assert.NoError(t, err)
+
+	cache.fs = afero.NewMemMapFs()
+
+	checkouts := []struct {
+		dir, url, ref, sha string
+	}{
+		{dir: "/a", url: "example.org/foo", ref: "v1.0.0"},
+		{dir: "/b", url: "example.org/foo", ref: "v2.0.0"},
+		{dir: "/c", url: "example.org/foo", sha: "abcdefg"},
+		{dir: "/d", url: "example.org/bar", ref: "v1.0.0"},
+	}
+
+	for _, c := range checkouts {
+		commit, err := cache.Checkout(context.Background(), c.dir, c.url, Auth{}, c.ref, c.sha, "operator", CheckoutOptions{})
+		assert.NoError(t, err)
+		assert.Equal(t, fmt.Sprintf("%s@%s%s", c.url, c.ref, c.sha), commit)
+	}
+
+	// Each repository is initialized once, each revision is fetched.
+	assert.Len(t, backend.inits, 2)
+	assert.Equal(t, map[string]int{"example.org/foo": 3, "example.org/bar": 1}, backend.fetches)
+	assert.Equal(t, map[string]string{
+		"/a": "example.org/foo@v1.0.0:operator",
+		"/b": "example.org/foo@v2.0.0:operator",
+		"/c": "example.org/foo@abcdefg:operator",
+		"/d": "example.org/bar@v1.0.0:operator",
+	}, backend.worktrees)
+
+	assert.NoError(t, cache.Remove())
+	assert.Empty(t, cache.repos)
+}
+
+func TestCacheVerifySHA(t *testing.T) {
+	backend := newFakeBackend()
+	backend.commits = map[string]string{"v1.0.0": "0123456789abcdef0123456789abcdef01234567"}
+
+	cache, err := NewCache("", backend)
+	assert.NoError(t, err)
+
+	cache.fs = afero.NewMemMapFs()
+
+	ctx := context.Background()
+
+	options := CheckoutOptions{}
+
+	_, err = cache.Checkout(ctx, "/a", "example.org/foo", Auth{}, "v1.0.0",
+		"0123456789ABCDEF0123456789ABCDEF01234567", "operator", options)
+	assert.NoError(t, err)
+
+	_, err = cache.Checkout(ctx, "/b", "example.org/foo", Auth{}, "v1.0.0",
+		"fedcba9876543210fedcba9876543210fedcba98", "operator", options)
+	assert.EqualError(t, err, `"v1.0.0" of "example.org/foo" points to commit `+
+		`"0123456789abcdef0123456789abcdef01234567" instead of the expected SHA `+
+		`"fedcba9876543210fedcba9876543210fedcba98"`)
+
+	// A prefix of the commit doesn't pin it.
+	_, err = cache.Checkout(ctx, "/c", "example.org/foo", Auth{}, "v1.0.0", "0", "operator", options)
+	assert.EqualError(t, err,
+		`SHA "0" pinning "v1.0.0" of "example.org/foo" has to be a full SHA of 40 hex characters`)
+
+	commit, err := cache.Commit(ctx, "example.org/foo", Auth{}, "v1.0.0")
+	assert.NoError(t, err)
+	assert.Equal(t, "0123456789abcdef0123456789abcdef01234567", commit)
+
+	assert.Equal(t, map[string]string{"/a": "0123456789abcdef0123456789abcdef01234567:operator"}, backend.worktrees)
+}
+
+func TestCacheSubmodules(t *testing.T) {
+	backend := newFakeBackend()
+	backend.submodules = map[string][]Submodule{
+		"https://example.org/operator.git@v1.0.0": {
+			{Path: "operator/templates", URL: "../templates.git", Commit: "1"},
+			{Path: "operator/crds", URL: "https://other.example.org/crds.git", Commit: "2"},
+		},
+	}
+
+	cache, err := NewCache("", backend)
+	assert.NoError(t, err)
+
+	cache.fs = afero.NewMemMapFs()
+
+	ctx := context.Background()
+
+	auth := Auth{Username: "kitt", Password: "secret"}
+
+	_, err = cache.Checkout(ctx, "/a", "https://example.org/operator.git", auth, "v1.0.0", "", "operator",
+		CheckoutOptions{Submodules: true})
+	assert.NoError(t, err)
+
+	// Credentials are only used for submodules on the same host.
+	assert.Equal(t, map[string]Auth{
+		"https://example.org/operator.git":   auth,
+		"https://example.org/templates.git":  auth,
+		"https://other.example.org/crds.git": {},
+	}, backend.auths)
+
+	backend.submodules["https://example.org/operator.git@v2.0.0"] = []Submodule{
+		{Path: "operator/templates", URL: "file:///etc", Commit: "1"},
+	}
+
+	_, err = cache.Checkout(ctx, "/b", "https://example.org/operator.git", auth, "v2.0.0", "", "operator",
+		CheckoutOptions{Submodules: true})
+	assert.EqualError(t, err,
+		`submodule "operator/templates" has the local URL "file:///etc", only remote URLs are supported`)
+}
+
+func TestCachePersistent(t *testing.T) {
+	fs := afero.NewMemMapFs()
+	backend := newFakeBackend()
+
+	// Every run uses the same repository directory.
+	for run := 0; run < 3; run++ {
+		cache := &Cache{
+			fs:      fs,
+			dir:     "/cache",
+			repos:   map[string]*cachedRepo{},
+			backend: backend,
+		}
+
+		ctx := context.Background()
+
+		_, err := cache.Checkout(ctx, "/a", "example.org/foo", Auth{}, "v1.0.0", "", "operator", CheckoutOptions{})
+		assert.NoError(t, err)
+
+		_, err = cache.Checkout(ctx, "/b", "example.org/foo", Auth{}, "v2.0.0", "", "operator", CheckoutOptions{})
+		assert.NoError(t, err)
+
+		assert.NoError(t, cache.Remove())
+	}
+
+	assert.Len(t, backend.inits, 1)
+
+	for dir, inits := range backend.inits {
+		assert.Contains(t, dir, "/cache/")
+		assert.Equal(t, 3, inits)
+	}
+}
@@ -0,0 +1,544 @@
+package git
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	neturl "net/url"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/afero"
+)
+
+// execBackend runs the 'git' binary.
+// Tags, branches and commits are fetched with a history depth of 1. All
+// branches and tags of a repository are only fetched if a server doesn't serve
+// a commit directly, at most once per repository. If the server supports it,
+// fetches leave out file contents, which are only fetched for the files of
+// the requested directory once it's checked out. Git LFS objects are fetched
+// by the 'git-lfs' binary.
+type execBackend struct {
+	fs afero.Fs
+
+	mu sync.Mutex
+
+	// Repositories whose branches and tags have been fetched.
+	fetched map[string]bool
+}
+
+func newExecBackend() *execBackend {
+	return &execBackend{
+		fs:      afero.NewOsFs(),
+		fetched: map[string]bool{},
+	}
+}
+
+func (b *execBackend) Init(ctx context.Context, repoDir string) error {
+	exists, err := afero.Exists(b.fs, filepath.Join(repoDir, "HEAD"))
+	if err != nil {
+		return err
+	}
+
+	if exists {
+		return nil
+	}
+
+	_, err = runAndLog(ctx, log.WithField("directory", repoDir), "git", "init", "--bare", repoDir)
+
+	return err
+}
+
+func (b *execBackend) Fetch(ctx context.Context, repoDir, url string, auth Auth, ref, sha string) (string, error) {
+	logger := log.WithField("url", url)
+
+	// Contents missing from a partial clone are fetched from the remote
+	// "origin", we fetch from it as well.
+	if _, err := runAndLog(ctx, logger, "git", "-C", repoDir, "config", "remote.origin.url", url); err != nil {
+		return "", err
+	}
+
+	switch {
+	case ref != "":
+		if err := fetch(ctx, logger, repoDir, url, auth, "--depth", "1", "--no-tags", blobFilter, "origin", ref); err != nil {
+			return "", err
+		}
+
+		return revParse(ctx, logger, repoDir, url, "FETCH_HEAD")
+	case fullSHA.MatchString(sha):
+		if commit, err := revParse(ctx, logger, repoDir, url, sha); err == nil {
+			logger.WithField("commit", commit).
+				Debug("Git commit has already been fetched")
+
+			return commit, nil
+		}
+
+		err := fetch(ctx, logger, repoDir, url, auth, "--depth", "1", "--no-tags", blobFilter, "origin", sha)
+		if err == nil {
+			return revParse(ctx, logger, repoDir, url, sha)
+		}
+
+		// Other errors, e.g. of authentication or the network, would fail
+		// fetching all branches and tags as well.
+		if !isUnservedObject(err) {
+			return "", err
+		}
+
+		logger.WithError(err).
+			Info("Git server doesn't serve commits directly, fetching all branches and tags")
+	}
+
+	// Abbreviated SHAs can't be fetched directly.
+	if err := b.fetchAll(ctx, logger, repoDir, url, auth); err != nil {
+		return "", err
+	}
+
+	return revParse(ctx, logger, repoDir, url, sha)
+}
+
+// blobFilter leaves out the contents of files when fetching, making the
+// repository a partial clone. Missing contents are fetched by Git once they
+// are needed. Servers that don't support filters ignore it.
+const blobFilter = "--filter=blob:none"
+
+// isUnservedObject returns whether a fetch of a commit failed because the
+// server doesn't allow fetching commits that aren't advertised by a branch or
+// tag.
+func isUnservedObject(err error) bool {
+	message := err.Error()
+
+	return strings.Contains(message, "not our ref") || strings.Contains(message, "unadvertised object")
+}
+
+// fetchAll fetches the complete history of all branches and tags once.
+func (b *execBackend) fetchAll(ctx context.Context, logger *log.Entry, repoDir, url string, auth Auth) error {
+	b.mu.Lock()
+	fetched := b.fetched[repoDir]
+	b.mu.Unlock()
+
+	if fetched {
+		return nil
+	}
+
+	args := []string{"--prune", "--force", blobFilter}
+
+	// Commits of earlier shallow fetches are missing their parents.
+	shallow, err := afero.Exists(b.fs, filepath.Join(repoDir, "shallow"))
+	if err != nil {
+		return err
+	}
+
+	if shallow {
+		args = append(args, "--unshallow")
+	}
+
+	args = append(args, "origin", "+refs/heads/*:refs/heads/*", "+refs/tags/*:refs/tags/*")
+
+	if err := fetch(ctx, logger, repoDir, url, auth, args...); err != nil {
+		return err
+	}
+
+	b.mu.Lock()
+	b.fetched[repoDir] = true
+	b.mu.Unlock()
+
+	return nil
+}
+
+func (b *execBackend) Tags(ctx context.Context, repoDir, url string, auth Auth) ([]string, error) {
+	output, err := runRemote(ctx, log.WithField("url", url), repoDir, url, auth, "ls-remote", "--tags", "--refs", url)
+	if err != nil {
+		return nil, err
+	}
+
+	tags := []string{}
+
+	// Lines have the format "<object>\t<reference>".
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.SplitN(line, "\t", 2)
+		if len(fields) == 2 && strings.HasPrefix(fields[1], "refs/tags/") {
+			tags = append(tags, strings.TrimPrefix(fields[1], "refs/tags/"))
+		}
+	}
+
+	return tags, nil
+}
+
+func (b *execBackend) Submodules(ctx context.Context, repoDir, url string, auth Auth, commit string) ([]Submodule, error) {
+	logger := log.WithField("commit", commit)
+
+	entries, err := lsTree(ctx, logger, repoDir, commit, "")
+	if err != nil {
+		return nil, err
+	}
+
+	gitlinks := map[string]string{}
+
+	for _, entry := range entries {
+		if entry.objectType == "commit" {
+			gitlinks[entry.path] = entry.object
+		}
+	}
+
+	if len(gitlinks) == 0 {
+		return nil, nil
+	}
+
+	// The content of '.gitmodules' may not have been fetched yet.
+	gitmodules, err := runRemote(ctx, logger, repoDir, url, auth, "cat-file", "blob", commit+":.gitmodules")
+	if err != nil {
+		return nil, err
+	}
+
+	return parseSubmodules([]byte(gitmodules), gitlinks)
+}
+
+func (b *execBackend) FetchLFS(ctx context.Context, repoDir, url string, auth Auth, commit, directory string) error {
+	if _, err := exec.LookPath("git-lfs"); err != nil {
+		return errors.New("git-lfs isn't installed, it's needed to fetch Git LFS objects")
+	}
+
+	lfsURL := url
+
+	// git-lfs only accepts URLs of local repositories with a "file" scheme.
+	if filepath.IsAbs(url) {
+		lfsURL = "file://" + filepath.ToSlash(url)
+	}
+
+	logger := log.WithField("url", url)
+	pathspec := path.Clean("/" + directory)[1:]
+
+	// git-lfs reads the pointer files of the commit, which may not have been
+	// fetched into a partial clone yet.
+	if err := fetchMissingBlobs(ctx, logger, repoDir, url, auth, commit, pathspec); err != nil {
+		return err
+	}
+
+	args := []string{"fetch", lfsURL, commit}
+
+	if pathspec != "" {
+		args = append(args, "--include", pathspec)
+	}
+
+	_, err := runRemote(ctx, logger, repoDir, url, auth, "lfs", args...)
+
+	return err
+}
+
+// fetchMissingBlobs fetches the contents of the files in 'pathspec' of
+// a commit that are missing from a partial clone.
+func fetchMissingBlobs(
+	ctx context.Context,
+	logger *log.Entry,
+	repoDir, url string,
+	auth Auth,
+	commit, pathspec string,
+) error {
+	output, err := runAndLog(ctx, logger, "git", "-C", repoDir, "rev-list", "--objects", "--missing=print", commit)
+	if err != nil {
+		return err
+	}
+
+	missing := map[string]bool{}
+
+	for _, line := range strings.Split(output, "\n") {
+		if strings.HasPrefix(line, "?") {
+			missing[line[1:]] = true
+		}
+	}
+
+	if len(missing) == 0 {
+		return nil
+	}
+
+	entries, err := lsTree(ctx, logger, repoDir, commit, pathspec)
+	if err != nil {
+		return err
+	}
+
+	blobs := []string{}
+
+	for _, entry := range entries {
+		if missing[entry.object] {
+			blobs = append(blobs, entry.object)
+		}
+	}
+
+	if len(blobs) == 0 {
+		return nil
+	}
+
+	return fetch(ctx, logger, repoDir, url, auth, append([]string{"--no-tags", "--no-write-fetch-head", "origin"}, blobs...)...)
+}
+
+// treeEntry is a file, directory or submodule of a commit.
+type treeEntry struct {
+	objectType string
+	object     string
+	path       string
+}
+
+// lsTree lists the files and submodules in 'pathspec' of a commit
+// recursively. If 'pathspec' is empty, all of them are listed.
+func lsTree(ctx context.Context, logger *log.Entry, repoDir, commit, pathspec string) ([]treeEntry, error) {
+	args := []string{"-C", repoDir, "ls-tree", "-r", "-z", commit}
+	if pathspec != "" {
+		args = append(args, "--", pathspec)
+	}
+
+	output, err := runAndLog(ctx, logger, "git", args...)
+	if err != nil {
+		return nil, err
+	}
+
+	entries := []treeEntry{}
+
+	// Entries have the format "<mode> <type> <object>\t<path>".
+	for _, entry := range strings.Split(output, "\x00") {
+		fields := strings.SplitN(entry, "\t", 2)
+		if len(fields) != 2 {
+			continue
+		}
+
+		if info := strings.Fields(fields[0]); len(info) == 3 {
+			entries = append(entries, treeEntry{objectType: info[1], object: info[2], path: fields[1]})
+		}
+	}
+
+	return entries, nil
+}
+
+func (b *execBackend) Checkout(
+	ctx context.Context,
+	repoDir, worktreeDir, url string,
+	auth Auth,
+	commit, directory string,
+	lfs bool,
+) error {
+	logger := log.WithField("commit", commit)
+
+	// The index of the checkout is kept outside of the repository, so that
+	// checkouts don't interfere with each other.
+	indexDir, err := afero.TempDir(b.fs, "", "")
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		if err := b.fs.RemoveAll(indexDir); err != nil {
+			logger.WithError(err).Warn("Failed to remove temporary Git index")
+		}
+	}()
+
+	pathspec := path.Clean("/" + directory)[1:]
+	if pathspec == "" {
+		pathspec = "."
+	}
+
+	// The worktree is relative to the repository directory.
+	worktreeDir, err = filepath.Abs(worktreeDir)
+	if err != nil {
+		return err
+	}
+
+	// Contents of files that haven't been fetched yet are fetched by the
+	// checkout.
+	cmd := remoteCommand(ctx, repoDir, url, auth, "--work-tree", worktreeDir, "checkout", commit, "--", pathspec)
+	cmd.Env = append(cmd.Env, "GIT_INDEX_FILE="+filepath.Join(indexDir, "index"))
+
+	if _, err := run(logger, cmd); err != nil {
+		return err
+	}
+
+	if !lfs {
+		return nil
+	}
+
+	return b.smudgeLFS(ctx, logger, repoDir, filepath.Join(worktreeDir, pathspec))
+}
+
+// lfsPointerPrefix starts the content of Git LFS pointer files.
+const lfsPointerPrefix = "version https://git-lfs.github.com/spec/"
+
+// lfsPointerMaxSize is the maximum size of Git LFS pointer files.
+const lfsPointerMaxSize = 1024
+
+// smudgeLFS replaces the Git LFS pointer files in 'dir' by the content of
+// their fetched objects.
+// Git LFS attributes of a partial checkout may be missing, so pointer files
+// are detected by their content instead.
+func (b *execBackend) smudgeLFS(ctx context.Context, logger *log.Entry, repoDir, dir string) error {
+	return afero.Walk(b.fs, dir, func(name string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if !info.Mode().IsRegular() || info.Size() > lfsPointerMaxSize {
+			return nil
+		}
+
+		pointer, err := afero.ReadFile(b.fs, name)
+		if err != nil {
+			return err
+		}
+
+		if !strings.HasPrefix(string(pointer), lfsPointerPrefix) {
+			return nil
+		}
+
+		logger.WithField("file", name).
+			Debug("Replacing Git LFS pointer")
+
+		//nolint:gosec
+		cmd := exec.CommandContext(ctx, "git", "-C", repoDir, "lfs", "smudge")
+		cmd.Stdin = strings.NewReader(string(pointer))
+
+		content, err := run(logger, cmd)
+		if err != nil {
+			return err
+		}
+
+		return afero.WriteFile(b.fs, name, []byte(content), info.Mode().Perm())
+	})
+}
+
+// credentialHelper answers Git's credential requests with the username and
+// password of the environment variables set by 'remoteCommand'.
+const credentialHelper = `!f() { test "$1" = get && echo "username=${KITT_GIT_USERNAME}" && ` +
+	`echo "password=${KITT_GIT_PASSWORD}"; }; f`
+
+// fetch runs 'git fetch' in a repository with the credentials of 'auth' for
+// 'url'.
+func fetch(ctx context.Context, logger *log.Entry, repoDir, url string, auth Auth, args ...string) error {
+	_, err := runRemote(ctx, logger, repoDir, url, auth, "fetch", args...)
+
+	return err
+}
+
+// runRemote runs a Git command accessing the remote repository at 'url' with
+// the credentials of 'auth' and returns its standard output.
+// Secrets are passed in environment variables instead of arguments, so that
+// they don't show up in process lists or logs.
+func runRemote(
+	ctx context.Context,
+	logger *log.Entry,
+	repoDir, url string,
+	auth Auth,
+	command string,
+	args ...string,
+) (string, error) {
+	return run(logger, remoteCommand(ctx, repoDir, url, auth, append([]string{command}, args...)...))
+}
+
+// remoteCommand prepares a Git command like 'runRemote'.
+// The username and password of 'auth' are only provided for the scheme, host
+// and port of 'url', Git doesn't get them for other hosts, e.g. of redirects.
+func remoteCommand(ctx context.Context, repoDir, url string, auth Auth, args ...string) *exec.Cmd {
+	gitArgs := []string{"-C", repoDir}
+
+	// Git must fail instead of waiting for credentials to be entered.
+	env := append(os.Environ(), "GIT_TERMINAL_PROMPT=0")
+
+	if auth.Username != "" || auth.Password != "" {
+		// The empty helper resets the credential helpers configured on the host.
+		gitArgs = append(gitArgs, "-c", "credential.helper=")
+
+		if origin := credentialOrigin(url); origin != "" {
+			gitArgs = append(gitArgs, "-c", "credential."+origin+".helper="+credentialHelper)
+		}
+
+		env = append(env, "KITT_GIT_USERNAME="+auth.Username, "KITT_GIT_PASSWORD="+auth.Password)
+	}
+
+	if auth.SSHKey != "" {
+		env = append(env, "GIT_SSH_COMMAND=ssh -i "+shellQuote(auth.SSHKey)+" -o IdentitiesOnly=yes")
+	}
+
+	//nolint:gosec
+	cmd := exec.CommandContext(ctx, "git", append(gitArgs, args...)...)
+	cmd.Env = env
+
+	return cmd
+}
+
+// credentialOrigin returns the scheme, host and port of an HTTP 'url' that
+// Git matches credential requests against. It returns an empty string for
+// other URLs, which don't use passwords.
+func credentialOrigin(url string) string {
+	u, err := neturl.Parse(url)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return ""
+	}
+
+	return u.Scheme + "://" + u.Host
+}
+
+// shellQuote quotes 's' as a single word for a POSIX shell.
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'"'"'`) + "'"
+}
+
+// revParse returns the SHA of the commit 'revision' points to.
+func revParse(ctx context.Context, logger *log.Entry, repoDir, url, revision string) (string, error) {
+	output, err := runAndLog(ctx, logger, "git", "-C", repoDir, "rev-parse", "--verify", "--quiet",
+		revision+"^{commit}")
+	if err != nil {
+		return "", fmt.Errorf("revision %q not found in %q", revision, url)
+	}
+
+	return strings.TrimSpace(output), nil
+}
+
+// runAndLog runs a command and returns its standard output. The command's
+// standard error is logged while it runs and is part of the returned error
+// if the command fails.
+func runAndLog(ctx context.Context, logger *log.Entry, name string, args ...string) (string, error) {
+	//nolint:gosec
+	return run(logger, exec.CommandContext(ctx, name, args...))
+}
+
+// run runs a prepared command like 'runAndLog'.
+func run(logger *log.Entry, cmd *exec.Cmd) (string, error) {
+	var stdout strings.Builder
+
+	cmd.Stdout = &stdout
+
+	stderr, err := cmd.StderrPipe()
+	if err != nil {
+		return "", err
+	}
+
+	if err := cmd.Start(); err != nil {
+		return "", err
+	}
+
+	var output []string
+
+	// Output git's command output while running the command.
+	scanner := bufio.NewScanner(stderr)
+
+	for scanner.Scan() {
+		t := scanner.Text()
+		logger.Debug(t)
+
+		output = append(output, t)
+	}
+
+	if err := cmd.Wait(); err != nil {
+		command := strings.Join(cmd.Args, " ")
+
+		if len(output) == 0 {
+			return "", fmt.Errorf("%q failed: %v", command, err)
+		}
+
+		return "", fmt.Errorf("%q failed: %v: %s", command, err, strings.Join(output, "\n"))
+	}
+
+	return stdout.String(), nil
+}
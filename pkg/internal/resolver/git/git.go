@@ -1,10 +1,8 @@
 package git
 
 import (
-	"bufio"
 	"context"
 	"errors"
-	"os/exec"
 	"path"
 
 	log "github.com/sirupsen/logrus"
@@ -14,53 +12,101 @@ import (
 // Resolver resolves operator packages from a Git repository.
 type Resolver struct {
 	URL               string
+	Auth              Auth
+	Tag               string
 	Branch            string
 	SHA               string
 	OperatorDirectory string
+	Options           CheckoutOptions
 
 	// Extracted function to simplify testing.
-	gitClone func(ctx context.Context, tempDir, url, branch, sha string) error
+	gitCheckout func(
+		ctx context.Context,
+		tempDir, url string,
+		auth Auth,
+		ref, sha, directory string,
+		options CheckoutOptions,
+	) (string, error)
 }
 
-// NewResolver creates a new Resolver for a Git repository at the specified URL.
-func NewResolver(url, branch, sha string, operatorDirectory string) Resolver {
+// NewResolver creates a new Resolver for a Git repository at the specified URL,
+// accessed with the credentials of 'auth'.
+// The repository is cloned through 'cache', so that resolvers of the same
+// repository share a single clone. 'options' enable submodules and Git LFS.
+func NewResolver(
+	cache *Cache,
+	url string,
+	auth Auth,
+	tag, branch, sha string,
+	operatorDirectory string,
+	options CheckoutOptions,
+) Resolver {
 	return Resolver{
 		URL:               url,
+		Auth:              auth,
+		Tag:               tag,
 		Branch:            branch,
 		SHA:               sha,
 		OperatorDirectory: operatorDirectory,
+		Options:           options,
 
-		gitClone: gitClone,
+		gitCheckout: cache.Checkout,
 	}
 }
 
-// Resolve clones a specific branch of a git repository and returns a file system
-// pointing at the operator directory.
-// The repository is cloned into a temporary directory. Callers are responsible
-// for removing this directory by running the returned remover function.
+// TagRef returns the full reference name of a tag.
+func TagRef(tag string) string {
+	return "refs/tags/" + tag
+}
+
+// BranchRef returns the full reference name of a branch.
+func BranchRef(branch string) string {
+	return "refs/heads/" + branch
+}
+
+// Resolve checks out the operator directory of a specific tag, branch or
+// commit of a git repository and returns a file system pointing at it.
+// The revision is checked out into a temporary directory. Callers are
+// responsible for removing this directory by running the returned remover
+// function.
 func (r Resolver) Resolve(ctx context.Context) (afero.Fs, func() error, error) {
+	fs, remover, _, err := r.ResolveCommit(ctx)
+
+	return fs, remover, err
+}
+
+// ResolveCommit works like 'Resolve', but also returns the SHA of the checked
+// out commit. For branches, this is the commit the branch currently points to.
+func (r Resolver) ResolveCommit(ctx context.Context) (afero.Fs, func() error, string, error) {
 	fs := afero.NewOsFs()
 
-	if r.Branch == "" && r.SHA == "" {
-		return nil, nil, errors.New("neither branch nor SHA provided")
+	ref := ""
+
+	switch {
+	case r.Tag != "" && r.Branch != "":
+		return nil, nil, "", errors.New("either tag or branch can be provided, not both")
+	case r.Tag != "":
+		ref = TagRef(r.Tag)
+	case r.Branch != "":
+		ref = BranchRef(r.Branch)
+	case r.SHA == "":
+		return nil, nil, "", errors.New("neither tag, branch nor SHA provided")
 	}
 
 	tempDir, err := afero.TempDir(fs, "", "")
 	if err != nil {
-		return nil, nil, err
+		return nil, nil, "", err
 	}
 
 	log.WithField("directory", tempDir).
 		Debug("Created temporary directory")
 
-	log.WithField("url", r.URL).
+	logger := log.WithField("url", r.URL).
+		WithField("tag", r.Tag).
 		WithField("branch", r.Branch).
-		WithField("sha", r.SHA).
-		Info("Cloning Git repository")
+		WithField("sha", r.SHA)
 
-	if err := r.gitClone(ctx, tempDir, r.URL, r.Branch, r.SHA); err != nil {
-		return nil, nil, err
-	}
+	logger.Info("Checking out Git repository")
 
 	remover := func() error {
 		log.WithField("directory", tempDir).
@@ -68,55 +114,17 @@ func (r Resolver) Resolve(ctx context.Context) (afero.Fs, func() error, error) {
 		return fs.RemoveAll(tempDir)
 	}
 
-	return afero.NewBasePathFs(fs, path.Join(tempDir, r.OperatorDirectory)), remover, nil
-}
-
-func gitClone(ctx context.Context, tempDir, url, branch, sha string) error {
-	if branch != "" {
-		logger := log.WithField("url", url).WithField("branch", branch)
-
-		if err := runAndLog(ctx, logger, "git", "clone", "--branch", branch, "--single-branch", url, tempDir); err != nil {
-			return err
-		}
-	} else {
-		logger := log.WithField("url", url).WithField("sha", sha)
-
-		if err := runAndLog(ctx, logger, "git", "clone", url, tempDir); err != nil {
-			return err
-		}
-
-		if err := runAndLog(ctx, logger, "git", "-C", tempDir, "checkout", sha); err != nil {
-			return err
-		}
-	}
-
-	return nil
-}
-
-func runAndLog(ctx context.Context, logger *log.Entry, name string, args ...string) error {
-	//nolint:gosec
-	cmd := exec.CommandContext(ctx, name, args...)
-
-	stderr, err := cmd.StderrPipe()
+	commit, err := r.gitCheckout(ctx, tempDir, r.URL, r.Auth, ref, r.SHA, r.OperatorDirectory, r.Options)
 	if err != nil {
-		return err
-	}
-
-	if err := cmd.Start(); err != nil {
-		return err
-	}
-
-	// Output git's command output while running the command.
-	scanner := bufio.NewScanner(stderr)
+		if rerr := remover(); rerr != nil {
+			log.WithError(rerr).Warn("Failed to remove temporary directory")
+		}
 
-	for scanner.Scan() {
-		t := scanner.Text()
-		logger.Debug(t)
+		return nil, nil, "", err
 	}
 
-	if err := cmd.Wait(); err != nil {
-		return err
-	}
+	logger.WithField("commit", commit).
+		Info("Checked out Git commit")
 
-	return nil
+	return afero.NewBasePathFs(fs, path.Join(tempDir, r.OperatorDirectory)), remover, commit, nil
 }
@@ -11,42 +11,50 @@ import (
 func TestResolve(t *testing.T) {
 	tests := []struct {
 		name      string
-		cloneFake func(context.Context, string, string, string, string) error
+		tag       string
 		branch    string
 		sha       string
+		options   CheckoutOptions
+		ref       string
+		commit    string
 		expectErr bool
 	}{
 		{
-			name: "resolve branch",
-			cloneFake: func(ctx context.Context, tempDir, url, branch, sha string) error {
-				if url == "example.org" && branch == "test" && sha == "" {
-					return nil
-				}
-
-				return errors.New("wrong URL and branch")
-			},
+			name:      "resolve tag",
+			tag:       "v1.0.0",
+			ref:       "refs/tags/v1.0.0",
+			commit:    "0123456789abcdef",
+			expectErr: false,
+		},
+		{
+			name:      "resolve branch",
 			branch:    "test",
-			sha:       "",
+			ref:       "refs/heads/test",
+			commit:    "0123456789abcdef",
 			expectErr: false,
 		},
 		{
-			name: "resolve SHA",
-			cloneFake: func(ctx context.Context, tempDir, url, branch, sha string) error {
-				if url == "example.org" && branch == "" && sha == "abcdefg" {
-					return nil
-				}
-
-				return errors.New("wrong URL and SHA")
-			},
-			branch:    "",
+			name:      "resolve SHA",
 			sha:       "abcdefg",
+			commit:    "abcdefg0123456789",
+			expectErr: false,
+		},
+		{
+			name:      "resolve with submodules and LFS",
+			tag:       "v1.0.0",
+			options:   CheckoutOptions{Submodules: true, LFS: true},
+			ref:       "refs/tags/v1.0.0",
+			commit:    "0123456789abcdef",
 			expectErr: false,
 		},
 		{
-			name:      "neither branch nor SHA set",
-			cloneFake: nil,
-			branch:    "",
-			sha:       "",
+			name:      "both tag and branch set",
+			tag:       "v1.0.0",
+			branch:    "test",
+			expectErr: true,
+		},
+		{
+			name:      "neither tag, branch nor SHA set",
 			expectErr: true,
 		},
 	}
@@ -55,15 +63,32 @@ func TestResolve(t *testing.T) {
 		test := test
 
 		t.Run(test.name, func(t *testing.T) {
+			checkoutFake := func(
+				ctx context.Context,
+				tempDir, url string,
+				auth Auth,
+				ref, sha, directory string,
+				options CheckoutOptions,
+			) (string, error) {
+				if url == "example.org" && ref == test.ref && sha == test.sha && directory == "operator" &&
+					options == test.options {
+					return test.commit, nil
+				}
+
+				return "", errors.New("wrong URL, revision or options")
+			}
+
 			resolver := &Resolver{
 				URL:               "example.org",
+				Tag:               test.tag,
 				Branch:            test.branch,
 				SHA:               test.sha,
 				OperatorDirectory: "operator",
-				gitClone:          test.cloneFake,
+				Options:           test.options,
+				gitCheckout:       checkoutFake,
 			}
 
-			_, remover, err := resolver.Resolve(context.Background())
+			_, remover, commit, err := resolver.ResolveCommit(context.Background())
 
 			if remover != nil {
 				defer func() {
@@ -75,6 +100,7 @@ func TestResolve(t *testing.T) {
 				assert.Error(t, err)
 			} else {
 				assert.NoError(t, err)
+				assert.Equal(t, test.commit, commit)
 			}
 		})
 	}
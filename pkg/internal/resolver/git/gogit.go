@@ -0,0 +1,406 @@
+package git
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/filemode"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	githttp "github.com/go-git/go-git/v5/plumbing/transport/http"
+	gitssh "github.com/go-git/go-git/v5/plumbing/transport/ssh"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/afero"
+)
+
+// fetchRef is a temporary reference for shallow fetches.
+// Shallow commits must not be referenced, otherwise later fetches fail while
+// looking for their missing parents.
+const fetchRef = plumbing.ReferenceName("refs/kitt/fetch")
+
+// fullSHA matches complete commit SHAs, which can be fetched directly.
+var fullSHA = regexp.MustCompile("^[0-9a-f]{40}$")
+
+// goBackend uses go-git.
+// Only the requested commits are fetched with a history depth of 1. Files of
+// a commit are written directly into the worktree directory, which isn't
+// registered as a worktree of the repository. Only the files of the requested
+// directory are written. Git LFS isn't supported.
+type goBackend struct{}
+
+func (goBackend) Init(ctx context.Context, repoDir string) error {
+	_, err := git.PlainOpen(repoDir)
+	if err == nil {
+		return nil
+	}
+
+	if !errors.Is(err, git.ErrRepositoryNotExists) {
+		return fmt.Errorf("failed to open repository %q: %v", repoDir, err)
+	}
+
+	if _, err := git.PlainInit(repoDir, true); err != nil {
+		return fmt.Errorf("failed to create repository %q: %v", repoDir, err)
+	}
+
+	return nil
+}
+
+func (goBackend) Fetch(ctx context.Context, repoDir, url string, auth Auth, ref, sha string) (string, error) {
+	repo, err := git.PlainOpen(repoDir)
+	if err != nil {
+		return "", fmt.Errorf("failed to open repository %q: %v", repoDir, err)
+	}
+
+	method, err := authMethod(url, auth)
+	if err != nil {
+		return "", err
+	}
+
+	remote := git.NewRemote(repo.Storer, &config.RemoteConfig{Name: "origin", URLs: []string{url}})
+
+	var want plumbing.Hash
+
+	// Advertised references are fetched by name, commits by SHA.
+	var src string
+
+	switch {
+	case ref != "":
+		var name plumbing.ReferenceName
+
+		name, want, err = lookupRef(remote, method, url, ref)
+		if err != nil {
+			return "", err
+		}
+
+		src = name.String()
+	case fullSHA.MatchString(sha):
+		want = plumbing.NewHash(sha)
+		src = sha
+	default:
+		// Abbreviated SHAs can't be fetched directly.
+		return fetchAll(ctx, repo, remote, method, url, sha)
+	}
+
+	if _, err := repo.Storer.EncodedObject(plumbing.AnyObject, want); err == nil {
+		log.WithField("url", url).
+			WithField("object", want.String()).
+			Debug("Git object has already been fetched")
+
+		return commitOf(repo, want)
+	}
+
+	log.WithField("url", url).
+		WithField("object", want.String()).
+		Debug("Fetching Git object")
+
+	// A reference left over by an interrupted fetch must not be used as
+	// a starting point of this fetch.
+	if err := repo.Storer.RemoveReference(fetchRef); err != nil {
+		return "", err
+	}
+
+	err = remote.FetchContext(ctx, &git.FetchOptions{
+		RefSpecs: []config.RefSpec{config.RefSpec(fmt.Sprintf("+%s:%s", src, fetchRef))},
+		Depth:    1,
+		Auth:     method,
+		Tags:     git.NoTags,
+		Force:    true,
+	})
+
+	if rerr := repo.Storer.RemoveReference(fetchRef); rerr != nil && err == nil {
+		err = rerr
+	}
+
+	switch {
+	case errors.Is(err, git.ErrExactSHA1NotSupported):
+		log.WithField("url", url).
+			Info("Git server doesn't serve commits directly, fetching all branches and tags")
+
+		return fetchAll(ctx, repo, remote, method, url, want.String())
+	case err != nil && !errors.Is(err, git.NoErrAlreadyUpToDate):
+		return "", fmt.Errorf("failed to fetch %q from %q: %v", want, url, err)
+	}
+
+	return commitOf(repo, want)
+}
+
+func (goBackend) Tags(ctx context.Context, repoDir, url string, auth Auth) ([]string, error) {
+	repo, err := git.PlainOpen(repoDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open repository %q: %v", repoDir, err)
+	}
+
+	method, err := authMethod(url, auth)
+	if err != nil {
+		return nil, err
+	}
+
+	remote := git.NewRemote(repo.Storer, &config.RemoteConfig{Name: "origin", URLs: []string{url}})
+
+	refs, err := remote.List(&git.ListOptions{Auth: method})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list references of %q: %v", url, err)
+	}
+
+	tags := []string{}
+
+	for _, ref := range refs {
+		if ref.Name().IsTag() {
+			tags = append(tags, ref.Name().Short())
+		}
+	}
+
+	return tags, nil
+}
+
+func (goBackend) Submodules(ctx context.Context, repoDir, url string, auth Auth, commit string) ([]Submodule, error) {
+	tree, err := commitTree(repoDir, commit)
+	if err != nil {
+		return nil, err
+	}
+
+	walker := object.NewTreeWalker(tree, true, nil)
+	defer walker.Close()
+
+	gitlinks := map[string]string{}
+
+	for {
+		name, entry, err := walker.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+
+		if err != nil {
+			return nil, fmt.Errorf("failed to read tree of commit %q: %v", commit, err)
+		}
+
+		if entry.Mode == filemode.Submodule {
+			gitlinks[name] = entry.Hash.String()
+		}
+	}
+
+	if len(gitlinks) == 0 {
+		return nil, nil
+	}
+
+	f, err := tree.File(".gitmodules")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read .gitmodules of commit %q: %v", commit, err)
+	}
+
+	gitmodules, err := f.Contents()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read .gitmodules of commit %q: %v", commit, err)
+	}
+
+	return parseSubmodules([]byte(gitmodules), gitlinks)
+}
+
+func (goBackend) FetchLFS(ctx context.Context, repoDir, url string, auth Auth, commit, directory string) error {
+	return errLFSUnsupported()
+}
+
+func errLFSUnsupported() error {
+	return fmt.Errorf("the %q Git backend doesn't support Git LFS, use the %q backend", BackendGo, BackendExec)
+}
+
+func (goBackend) Checkout(
+	ctx context.Context,
+	repoDir, worktreeDir, url string,
+	auth Auth,
+	commit, directory string,
+	lfs bool,
+) error {
+	if lfs {
+		return errLFSUnsupported()
+	}
+
+	tree, err := commitTree(repoDir, commit)
+	if err != nil {
+		return err
+	}
+
+	directory = path.Clean("/" + directory)[1:]
+	if directory != "" {
+		tree, err = tree.Tree(directory)
+		if err != nil {
+			return fmt.Errorf("failed to read directory %q of commit %q: %v", directory, commit, err)
+		}
+	}
+
+	return writeTree(afero.NewBasePathFs(afero.NewOsFs(), filepath.Join(worktreeDir, directory)), tree)
+}
+
+// commitTree returns the tree of a commit of the repository in 'repoDir'.
+func commitTree(repoDir, commit string) (*object.Tree, error) {
+	repo, err := git.PlainOpen(repoDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open repository %q: %v", repoDir, err)
+	}
+
+	c, err := repo.CommitObject(plumbing.NewHash(commit))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read commit %q: %v", commit, err)
+	}
+
+	tree, err := c.Tree()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read tree of commit %q: %v", commit, err)
+	}
+
+	return tree, nil
+}
+
+// lookupRef returns the full name of a branch or tag of a remote repository
+// and the object it points to.
+func lookupRef(
+	remote *git.Remote,
+	method transport.AuthMethod,
+	url, ref string,
+) (plumbing.ReferenceName, plumbing.Hash, error) {
+	refs, err := remote.List(&git.ListOptions{Auth: method})
+	if err != nil {
+		return "", plumbing.ZeroHash, fmt.Errorf("failed to list references of %q: %v", url, err)
+	}
+
+	candidates := []plumbing.ReferenceName{
+		plumbing.ReferenceName(ref),
+		plumbing.NewTagReferenceName(ref),
+		plumbing.NewBranchReferenceName(ref),
+	}
+
+	for _, candidate := range candidates {
+		for _, r := range refs {
+			if r.Name() == candidate && r.Type() == plumbing.HashReference {
+				return r.Name(), r.Hash(), nil
+			}
+		}
+	}
+
+	return "", plumbing.ZeroHash, fmt.Errorf("reference %q not found in %q", ref, url)
+}
+
+// fetchAll fetches all branches and tags and resolves a revision.
+func fetchAll(
+	ctx context.Context,
+	repo *git.Repository,
+	remote *git.Remote,
+	method transport.AuthMethod,
+	url, revision string,
+) (string, error) {
+	err := remote.FetchContext(ctx, &git.FetchOptions{
+		RefSpecs: []config.RefSpec{"+refs/heads/*:refs/heads/*", "+refs/tags/*:refs/tags/*"},
+		Auth:     method,
+		Tags:     git.NoTags,
+		Force:    true,
+	})
+	if err != nil && !errors.Is(err, git.NoErrAlreadyUpToDate) {
+		return "", fmt.Errorf("failed to fetch %q: %v", url, err)
+	}
+
+	hash, err := repo.ResolveRevision(plumbing.Revision(revision))
+	if err != nil {
+		return "", fmt.Errorf("revision %q not found in %q: %v", revision, url, err)
+	}
+
+	return commitOf(repo, *hash)
+}
+
+// authMethod returns the authentication method matching the protocol of 'url'.
+// It returns nil if 'auth' doesn't provide credentials for the protocol.
+func authMethod(url string, auth Auth) (transport.AuthMethod, error) {
+	endpoint, err := transport.NewEndpoint(url)
+	if err != nil {
+		return nil, fmt.Errorf("invalid Git URL %q: %v", url, err)
+	}
+
+	switch endpoint.Protocol {
+	case "http", "https":
+		if auth.Username == "" && auth.Password == "" {
+			return nil, nil
+		}
+
+		return &githttp.BasicAuth{Username: auth.Username, Password: auth.Password}, nil
+	case "ssh":
+		if auth.SSHKey == "" {
+			return nil, nil
+		}
+
+		user := endpoint.User
+		if user == "" {
+			user = "git"
+		}
+
+		keys, err := gitssh.NewPublicKeysFromFile(user, auth.SSHKey, "")
+		if err != nil {
+			return nil, fmt.Errorf("failed to read SSH key %q: %v", auth.SSHKey, err)
+		}
+
+		return keys, nil
+	default:
+		return nil, nil
+	}
+}
+
+// commitOf returns the SHA of a commit, or of the commit an annotated tag
+// points to.
+func commitOf(repo *git.Repository, hash plumbing.Hash) (string, error) {
+	obj, err := repo.Object(plumbing.AnyObject, hash)
+	if err != nil {
+		return "", fmt.Errorf("failed to read object %q: %v", hash, err)
+	}
+
+	switch o := obj.(type) {
+	case *object.Commit:
+		return o.Hash.String(), nil
+	case *object.Tag:
+		c, err := o.Commit()
+		if err != nil {
+			return "", fmt.Errorf("failed to read commit of tag %q: %v", o.Name, err)
+		}
+
+		return c.Hash.String(), nil
+	default:
+		return "", fmt.Errorf("object %q isn't a commit", hash)
+	}
+}
+
+// writeTree writes the regular files of a tree into a file system.
+// Symbolic links and submodules are skipped.
+func writeTree(fs afero.Fs, tree *object.Tree) error {
+	return tree.Files().ForEach(func(f *object.File) error {
+		var mode os.FileMode
+
+		switch f.Mode {
+		case filemode.Regular, filemode.Deprecated:
+			mode = 0644
+		case filemode.Executable:
+			mode = 0755
+		default:
+			log.WithField("file", f.Name).
+				Debug("Skipping file that isn't a regular file")
+
+			return nil
+		}
+
+		content, err := f.Contents()
+		if err != nil {
+			return fmt.Errorf("failed to read %q: %v", f.Name, err)
+		}
+
+		if err := fs.MkdirAll(filepath.Dir(f.Name), 0755); err != nil {
+			return err
+		}
+
+		return afero.WriteFile(fs, f.Name, []byte(content), mode)
+	})
+}
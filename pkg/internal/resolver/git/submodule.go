@@ -0,0 +1,178 @@
+package git
+
+import (
+	"fmt"
+	neturl "net/url"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/go-git/go-git/v5/config"
+)
+
+// parseSubmodules combines the gitlinks of a commit, mapping paths to commits,
+// with the URLs of its '.gitmodules' file.
+func parseSubmodules(gitmodules []byte, gitlinks map[string]string) ([]Submodule, error) {
+	modules := config.NewModules()
+	if err := modules.Unmarshal(gitmodules); err != nil {
+		return nil, fmt.Errorf("failed to parse .gitmodules: %v", err)
+	}
+
+	urls := map[string]string{}
+
+	for _, module := range modules.Submodules {
+		if err := module.Validate(); err != nil {
+			return nil, fmt.Errorf("invalid submodule %q in .gitmodules: %v", module.Name, err)
+		}
+
+		urls[path.Clean(module.Path)] = module.URL
+	}
+
+	submodules := make([]Submodule, 0, len(gitlinks))
+
+	for p, commit := range gitlinks {
+		url, ok := urls[p]
+		if !ok {
+			return nil, fmt.Errorf("submodule %q isn't listed in .gitmodules", p)
+		}
+
+		submodules = append(submodules, Submodule{Path: p, URL: url, Commit: commit})
+	}
+
+	sort.Slice(submodules, func(i, j int) bool {
+		return submodules[i].Path < submodules[j].Path
+	})
+
+	return submodules, nil
+}
+
+// submoduleCheckout is a checkout of a submodule within the checkout of its
+// parent repository.
+type submoduleCheckout struct {
+	Submodule
+
+	// Directory of the submodule to check out.
+	directory string
+}
+
+// submoduleCheckouts returns the checkouts of the submodules of a repository
+// at 'url' needed to check out 'directory'. These are the submodules in
+// 'directory', or the submodule containing it. In the latter case, 'inside' is
+// true and nothing has to be checked out from the repository itself.
+// Submodules with local URLs are rejected, repositories must not make us read
+// local files.
+func submoduleCheckouts(
+	submodules []Submodule,
+	url, directory string,
+) (checkouts []submoduleCheckout, inside bool, err error) {
+	directory = path.Clean("/" + directory)[1:]
+
+	for _, submodule := range submodules {
+		subURL, err := submoduleURL(url, submodule.URL)
+		if err != nil {
+			return nil, false, err
+		}
+
+		checkout := submoduleCheckout{
+			Submodule: Submodule{Path: submodule.Path, URL: subURL, Commit: submodule.Commit},
+		}
+
+		inside := directory == submodule.Path || strings.HasPrefix(directory, submodule.Path+"/")
+
+		if !inside && directory != "" && !strings.HasPrefix(submodule.Path, directory+"/") {
+			continue
+		}
+
+		if isLocalURL(subURL) {
+			return nil, false, fmt.Errorf("submodule %q has the local URL %q, only remote URLs are supported",
+				submodule.Path, submodule.URL)
+		}
+
+		if inside {
+			checkout.directory = strings.TrimPrefix(directory[len(submodule.Path):], "/")
+
+			return []submoduleCheckout{checkout}, true, nil
+		}
+
+		checkouts = append(checkouts, checkout)
+	}
+
+	return checkouts, false, nil
+}
+
+// submoduleURL resolves the URL of a submodule. URLs starting with "./" or
+// "../" are relative to the URL of the parent repository, like for
+// 'git submodule'.
+func submoduleURL(parent, url string) (string, error) {
+	if !strings.HasPrefix(url, "./") && !strings.HasPrefix(url, "../") {
+		return url, nil
+	}
+
+	base := strings.TrimSuffix(parent, "/")
+	separator := "/"
+	relative := url
+
+	for {
+		switch {
+		case strings.HasPrefix(url, "./"):
+			url = url[len("./"):]
+		case strings.HasPrefix(url, "../"):
+			// Remote URLs like "host:path" separate the path by a colon.
+			i := strings.LastIndexAny(base, "/:")
+			if i <= 0 {
+				return "", fmt.Errorf("relative submodule URL %q points outside of %q", relative, parent)
+			}
+
+			separator = base[i : i+1]
+			base = base[:i]
+			url = url[len("../"):]
+		default:
+			return base + separator + url, nil
+		}
+	}
+}
+
+// isLocalURL returns whether 'url' is a "file://" URL or a path of the local
+// file system, like Git distinguishes them from remote URLs.
+func isLocalURL(url string) bool {
+	if strings.HasPrefix(strings.ToLower(url), "file:") {
+		return true
+	}
+
+	if strings.Contains(url, "://") {
+		return false
+	}
+
+	// Remote URLs like "host:path" have no slash before the colon.
+	i := strings.Index(url, ":")
+
+	return i <= 0 || strings.Contains(url[:i], "/")
+}
+
+// urlHost returns the host of a remote Git URL, or an empty string if it has
+// none.
+func urlHost(rawURL string) string {
+	if isLocalURL(rawURL) {
+		return ""
+	}
+
+	if strings.Contains(rawURL, "://") {
+		u, err := neturl.Parse(rawURL)
+		if err != nil {
+			return ""
+		}
+
+		return strings.ToLower(u.Hostname())
+	}
+
+	host := rawURL[:strings.Index(rawURL, ":")]
+
+	return strings.ToLower(host[strings.LastIndex(host, "@")+1:])
+}
+
+// sameHost returns whether the remote Git URLs 'a' and 'b' have the same host.
+func sameHost(a, b string) bool {
+	host := urlHost(a)
+
+	return host != "" && host == urlHost(b)
+}
@@ -0,0 +1,135 @@
+package git
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseSubmodules(t *testing.T) {
+	gitmodules := []byte(`[submodule "templates"]
+	path = operator/templates
+	url = ../templates.git
+[submodule "unused"]
+	path = unused
+	url = https://example.org/unused.git
+`)
+
+	submodules, err := parseSubmodules(gitmodules, map[string]string{"operator/templates": "0123456789abcdef"})
+	assert.NoError(t, err)
+	assert.Equal(t, []Submodule{
+		{Path: "operator/templates", URL: "../templates.git", Commit: "0123456789abcdef"},
+	}, submodules)
+
+	_, err = parseSubmodules(gitmodules, map[string]string{"operator/other": "0123456789abcdef"})
+	assert.EqualError(t, err, `submodule "operator/other" isn't listed in .gitmodules`)
+}
+
+func TestSubmoduleCheckouts(t *testing.T) {
+	submodules := []Submodule{
+		{Path: "docs", URL: "https://example.org/docs.git", Commit: "1"},
+		{Path: "operator/templates", URL: "../templates.git", Commit: "2"},
+	}
+
+	tests := []struct {
+		name      string
+		directory string
+		checkouts []submoduleCheckout
+		inside    bool
+	}{
+		{
+			name:      "submodule in directory",
+			directory: "operator",
+			checkouts: []submoduleCheckout{
+				{Submodule: Submodule{Path: "operator/templates", URL: "https://example.org/templates.git", Commit: "2"}},
+			},
+		},
+		{
+			name:      "all submodules",
+			directory: "",
+			checkouts: []submoduleCheckout{
+				{Submodule: Submodule{Path: "docs", URL: "https://example.org/docs.git", Commit: "1"}},
+				{Submodule: Submodule{Path: "operator/templates", URL: "https://example.org/templates.git", Commit: "2"}},
+			},
+		},
+		{
+			name:      "directory in submodule",
+			directory: "/operator/templates/v1/",
+			checkouts: []submoduleCheckout{
+				{
+					Submodule: Submodule{Path: "operator/templates", URL: "https://example.org/templates.git", Commit: "2"},
+					directory: "v1",
+				},
+			},
+			inside: true,
+		},
+		{
+			name:      "no submodules",
+			directory: "operator-docs",
+		},
+	}
+
+	for _, test := range tests {
+		checkouts, inside, err := submoduleCheckouts(submodules, "https://example.org/operator.git", test.directory)
+		assert.NoError(t, err, test.name)
+		assert.Equal(t, test.checkouts, checkouts, test.name)
+		assert.Equal(t, test.inside, inside, test.name)
+	}
+}
+
+func TestSubmoduleURL(t *testing.T) {
+	tests := []struct {
+		parent   string
+		url      string
+		expected string
+	}{
+		{parent: "https://example.org/a/b.git", url: "https://example.org/c.git", expected: "https://example.org/c.git"},
+		{parent: "https://example.org/a/b.git", url: "../c.git", expected: "https://example.org/a/c.git"},
+		{parent: "https://example.org/a/b.git/", url: "../../c.git", expected: "https://example.org/c.git"},
+		{parent: "https://example.org/a/b.git", url: "./c.git", expected: "https://example.org/a/b.git/c.git"},
+		{parent: "git@example.org:a/b.git", url: "../c.git", expected: "git@example.org:a/c.git"},
+		{parent: "git@example.org:b.git", url: "../c.git", expected: "git@example.org:c.git"},
+		{parent: "/repos/b", url: "../c", expected: "/repos/c"},
+	}
+
+	for _, test := range tests {
+		url, err := submoduleURL(test.parent, test.url)
+		assert.NoError(t, err, test.url)
+		assert.Equal(t, test.expected, url, test.url)
+	}
+
+	_, err := submoduleURL("git@example.org:b.git", "../../c.git")
+	assert.EqualError(t, err, `relative submodule URL "../../c.git" points outside of "git@example.org:b.git"`)
+}
+
+func TestSubmoduleCheckoutsLocalURL(t *testing.T) {
+	for _, url := range []string{"file:///repos/templates.git", "/repos/templates.git", "../templates.git"} {
+		submodules := []Submodule{{Path: "operator/templates", URL: url, Commit: "1"}}
+
+		_, _, err := submoduleCheckouts(submodules, "/repos/operator.git", "operator")
+		assert.Error(t, err, url)
+
+		// Submodules outside of the directory aren't checked out.
+		_, _, err = submoduleCheckouts(submodules, "/repos/operator.git", "docs")
+		assert.NoError(t, err, url)
+	}
+}
+
+func TestSameHost(t *testing.T) {
+	tests := []struct {
+		a, b     string
+		expected bool
+	}{
+		{a: "https://example.org/a.git", b: "https://EXAMPLE.org:443/b.git", expected: true},
+		{a: "https://example.org/a.git", b: "git@example.org:b.git", expected: true},
+		{a: "ssh://git@example.org/a.git", b: "example.org:b.git", expected: true},
+		{a: "https://example.org/a.git", b: "https://example.org.evil.com/b.git", expected: false},
+		{a: "https://example.org/a.git", b: "https://other.example.org/b.git", expected: false},
+		{a: "/repos/a", b: "/repos/b", expected: false},
+		{a: "https://example.org/a.git", b: "file://example.org/b.git", expected: false},
+	}
+
+	for _, test := range tests {
+		assert.Equal(t, test.expected, sameHost(test.a, test.b), test.b)
+	}
+}
@@ -0,0 +1,70 @@
+package oci
+
+import (
+	"context"
+	"fmt"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/afero"
+
+	"github.com/kudobuilder/kitt/pkg/internal/oci"
+	"github.com/kudobuilder/kitt/pkg/internal/resolver/archive"
+)
+
+// Resolver resolves operator packages stored as artifacts in an OCI registry.
+type Resolver struct {
+	Reference string
+
+	client *oci.Client
+	limits archive.Limits
+}
+
+// NewResolver creates a new Resolver for an OCI reference like
+// "oci://registry/namespace/name:tag". Package layers are extracted within
+// 'limits'.
+func NewResolver(reference string, client *oci.Client, limits archive.Limits) Resolver {
+	return Resolver{
+		Reference: reference,
+		client:    client,
+		limits:    limits,
+	}
+}
+
+// Resolve pulls the operator package layer of an OCI artifact and extracts it
+// into a file system.
+func (r Resolver) Resolve(ctx context.Context) (afero.Fs, func() error, error) {
+	ref, err := oci.ParseReference(r.Reference)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	log.WithField("reference", ref.String()).
+		Info("Pulling operator artifact")
+
+	manifest, _, err := r.client.Manifest(ctx, ref)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	layer, err := manifest.PackageLayer()
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid operator artifact %q: %v", ref, err)
+	}
+
+	tarball, err := r.client.Blob(ctx, ref, layer)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	fs, err := archive.Extract(tarball, "", r.limits)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	remover := func() error {
+		// Nothing to clean up, because we're providing the file system in memory.
+		return nil
+	}
+
+	return fs, remover, nil
+}
@@ -0,0 +1,61 @@
+package oci
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"path/filepath"
+	"testing"
+
+	"github.com/kudobuilder/kudo/pkg/kudoctl/packages/writer"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/kudobuilder/kitt/pkg/internal/oci"
+	"github.com/kudobuilder/kitt/pkg/internal/oci/ocitest"
+	"github.com/kudobuilder/kitt/pkg/internal/resolver/archive"
+)
+
+func TestResolve(t *testing.T) {
+	registry := ocitest.NewRegistry()
+	defer registry.Close()
+
+	fs := afero.NewMemMapFs()
+	assert.NoError(t, afero.WriteFile(fs, filepath.Join("/", "operator", "operator.yaml"), []byte("name: foo"), 0644))
+
+	pkgFs := afero.NewBasePathFs(fs, filepath.Join("/", "operator"))
+
+	var tarball bytes.Buffer
+	assert.NoError(t, writer.TgzDir(pkgFs, "", &tarball))
+
+	ctx := context.Background()
+	client := oci.NewClient(http.DefaultClient, nil)
+
+	ref, err := oci.ParseReference(registry.Host() + "/kudo/foo:1.0.0")
+	assert.NoError(t, err)
+
+	config, err := client.PushBlob(ctx, ref, oci.MediaTypeConfig, []byte("{}"))
+	assert.NoError(t, err)
+
+	layer, err := client.PushBlob(ctx, ref, oci.MediaTypePackage, tarball.Bytes())
+	assert.NoError(t, err)
+
+	_, err = client.PushManifest(ctx, ref, oci.Manifest{
+		SchemaVersion: 2,
+		MediaType:     oci.MediaTypeManifest,
+		Config:        config,
+		Layers:        []oci.Descriptor{layer},
+	})
+	assert.NoError(t, err)
+
+	resolvedFs, remover, err := NewResolver("oci://"+ref.String(), client, archive.DefaultLimits()).Resolve(ctx)
+	assert.NoError(t, err)
+
+	content, err := afero.ReadFile(resolvedFs, filepath.Join("/", "operator.yaml"))
+	assert.NoError(t, err)
+	assert.Equal(t, "name: foo", string(content))
+	assert.NoError(t, remover())
+
+	_, _, err = NewResolver("oci://"+registry.Host()+"/kudo/foo:2.0.0", client, archive.DefaultLimits()).Resolve(ctx)
+	assert.Error(t, err)
+}
@@ -0,0 +1,48 @@
+package path
+
+import (
+	"context"
+	"fmt"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/afero"
+)
+
+// Resolver resolves operator packages from a local directory.
+type Resolver struct {
+	Path string
+
+	fs afero.Fs
+}
+
+// NewResolver creates a new Resolver for a local directory.
+func NewResolver(path string) Resolver {
+	return Resolver{
+		Path: path,
+
+		fs: afero.NewOsFs(),
+	}
+}
+
+// Resolve returns a read-only file system pointing at the operator directory.
+// The directory is used in place, nothing is copied.
+func (r Resolver) Resolve(ctx context.Context) (afero.Fs, func() error, error) {
+	info, err := r.fs.Stat(r.Path)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if !info.IsDir() {
+		return nil, nil, fmt.Errorf("%q isn't a directory", r.Path)
+	}
+
+	log.WithField("path", r.Path).
+		Info("Using local operator directory")
+
+	remover := func() error {
+		// Nothing to clean up, because the directory isn't ours.
+		return nil
+	}
+
+	return afero.NewReadOnlyFs(afero.NewBasePathFs(r.fs, r.Path)), remover, nil
+}
@@ -0,0 +1,39 @@
+package path
+
+import (
+	"context"
+	"testing"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestResolve(t *testing.T) {
+	fs := afero.NewMemMapFs()
+
+	assert.NoError(t, afero.WriteFile(fs, "/operators/foo/operator.yaml", []byte("name: foo"), 0644))
+
+	resolver := Resolver{Path: "/operators/foo", fs: fs}
+
+	pkgFs, remover, err := resolver.Resolve(context.Background())
+	assert.NoError(t, err)
+
+	content, err := afero.ReadFile(pkgFs, "/operator.yaml")
+	assert.NoError(t, err)
+	assert.Equal(t, "name: foo", string(content))
+
+	// The operator directory must not be changed.
+	assert.Error(t, afero.WriteFile(pkgFs, "/params.yaml", []byte{}, 0644))
+
+	assert.NoError(t, remover())
+
+	exists, err := afero.Exists(fs, "/operators/foo/operator.yaml")
+	assert.NoError(t, err)
+	assert.True(t, exists)
+
+	_, _, err = Resolver{Path: "/operators/foo/operator.yaml", fs: fs}.Resolve(context.Background())
+	assert.Error(t, err)
+
+	_, _, err = Resolver{Path: "/operators/bar", fs: fs}.Resolve(context.Background())
+	assert.Error(t, err)
+}
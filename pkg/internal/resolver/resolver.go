@@ -4,11 +4,19 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"path/filepath"
 
+	log "github.com/sirupsen/logrus"
 	"github.com/spf13/afero"
 
 	o "github.com/kudobuilder/kitt/pkg/internal/apis/operator"
+	"github.com/kudobuilder/kitt/pkg/internal/config"
+	"github.com/kudobuilder/kitt/pkg/internal/oci"
+	"github.com/kudobuilder/kitt/pkg/internal/repo"
+	"github.com/kudobuilder/kitt/pkg/internal/resolver/archive"
 	"github.com/kudobuilder/kitt/pkg/internal/resolver/git"
+	ociresolver "github.com/kudobuilder/kitt/pkg/internal/resolver/oci"
+	"github.com/kudobuilder/kitt/pkg/internal/resolver/path"
 	"github.com/kudobuilder/kitt/pkg/internal/resolver/url"
 )
 
@@ -17,22 +25,163 @@ type Resolver interface {
 	Resolve(context.Context) (fs afero.Fs, remover func() error, err error)
 }
 
+// Cache is shared by resolvers to avoid retrieving the same sources more than
+// once.
+type Cache struct {
+	git       *git.Cache
+	url       *url.Cache
+	urlClient *url.Client
+	oci       *oci.Client
+
+	limits archive.Limits
+
+	config config.Config
+}
+
+// Options configure how resolvers retrieve sources.
+type Options struct {
+	// CacheDir is a directory persisting sources between runs. If empty, Git
+	// repositories are only shared during a single run and URL tarballs aren't
+	// cached. Otherwise, Git repositories and URL tarballs are persisted in
+	// this directory and only fetched again if they changed.
+	CacheDir string
+
+	// GitBackend names the Git backend running Git operations, either "exec"
+	// or "go".
+	GitBackend string
+
+	// Config provides credentials of URL sources and OCI registries per host.
+	Config config.Config
+
+	// HTTP configures the client downloading URL sources.
+	HTTP url.ClientOptions
+
+	// Archive limits the extraction of URL and OCI package tarballs.
+	Archive archive.Limits
+}
+
+// NewCache creates a new cache for resolvers configured by 'options'.
+// Callers are responsible for removing temporary files of the cache by calling
+// 'Remove' once the cache is no longer needed.
+func NewCache(options Options) (*Cache, error) {
+	backend, err := git.NewBackend(options.GitBackend)
+	if err != nil {
+		return nil, err
+	}
+
+	urlClient, err := url.NewClient(options.HTTP)
+	if err != nil {
+		return nil, err
+	}
+
+	ociClient, err := newOCIClient(options)
+	if err != nil {
+		return nil, err
+	}
+
+	cache := &Cache{urlClient: urlClient, oci: ociClient, limits: options.Archive, config: options.Config}
+
+	if options.CacheDir == "" {
+		cache.git, err = git.NewCache("", backend)
+		if err != nil {
+			return nil, err
+		}
+
+		return cache, nil
+	}
+
+	cache.git, err = git.NewCache(filepath.Join(options.CacheDir, "git"), backend)
+	if err != nil {
+		return nil, err
+	}
+
+	cache.url, err = url.NewCache(filepath.Join(options.CacheDir, "url"))
+	if err != nil {
+		return nil, err
+	}
+
+	return cache, nil
+}
+
+// newOCIClient creates a client of OCI registries, sending requests with the
+// HTTP options of 'options'. Registries configured as hosts of
+// 'options.Config' use their credentials, other registries the credentials
+// stored in the Docker config file, if there are any.
+func newOCIClient(options Options) (*oci.Client, error) {
+	httpClient, err := url.NewHTTPClient(options.HTTP)
+	if err != nil {
+		return nil, err
+	}
+
+	docker := map[string]oci.Credentials{}
+
+	if path := oci.DockerConfigPath(); path != "" {
+		docker, err = oci.DockerCredentials(afero.NewOsFs(), path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read Docker credentials: %v", err)
+		}
+	}
+
+	return oci.NewClient(httpClient, registryCredentials(afero.NewOsFs(), options.Config, docker)), nil
+}
+
+// OCIClient returns the client of OCI registries of the cache, e.g. to push
+// packages with the same credentials.
+func (c *Cache) OCIClient() *oci.Client {
+	return c.oci
+}
+
+// Remove removes temporary files of the cache.
+func (c *Cache) Remove() error {
+	return c.git.Remove()
+}
+
 // New returns a new resolver for the kind of reference provided by 'version'.
-func New(operator o.Operator, version o.Version) (Resolver, error) {
+// Sources are retrieved through 'cache' to ensure that Git repositories are
+// only cloned once per source.
+func New(operator o.Operator, version o.Version, cache *Cache) (Resolver, error) {
 	if version.Git != nil {
-		source := findSource(operator.GitSources, version.Git.Source)
-		if source == nil {
-			return nil, fmt.Errorf("unknown git source %q", version.Git.Source)
+		if version.Git.TagPattern != "" {
+			return nil, errors.New("versions of a tag pattern have to be discovered before resolving them")
 		}
 
-		// TODO: cache git sources to ensure that repositories are only cloned once per source
-		resolver := git.NewResolver(source.URL, version.Git.Tag, version.Git.SHA, version.Git.Directory)
+		source, auth, err := gitSource(operator, version.Git.Source)
+		if err != nil {
+			return nil, err
+		}
+
+		options := git.CheckoutOptions{Submodules: source.Submodules, LFS: source.LFS}
+
+		resolver := git.NewResolver(
+			cache.git, source.URL, auth, version.Git.Tag, version.Git.Branch, version.Git.SHA, version.Git.Directory, options)
 
 		return resolver, nil
 	}
 
+	if version.Subdirectory != "" && version.URL == nil {
+		return nil, errors.New("subdirectory is only supported for url references")
+	}
+
 	if version.URL != nil {
-		resolver := url.NewResolver(*version.URL)
+		auth, err := urlAuth(afero.NewOsFs(), *version.URL, version.URLAuth, cache.config)
+		if err != nil {
+			return nil, fmt.Errorf("invalid auth of URL %q: %v", *version.URL, err)
+		}
+
+		resolver := url.NewResolver(
+			*version.URL, version.SHA256, version.Subdirectory, auth, cache.urlClient, cache.limits, cache.url)
+
+		return resolver, nil
+	}
+
+	if version.OCI != nil {
+		resolver := ociresolver.NewResolver(*version.OCI, cache.oci, cache.limits)
+
+		return resolver, nil
+	}
+
+	if version.Path != nil {
+		resolver := path.NewResolver(*version.Path)
 
 		return resolver, nil
 	}
@@ -40,6 +189,47 @@ func New(operator o.Operator, version o.Version) (Resolver, error) {
 	return nil, errors.New("unknown version resolver")
 }
 
+// GitCommit returns the SHA of the commit the tag of the Git reference of
+// 'version' currently points to. Repositories are fetched through 'cache'.
+func GitCommit(ctx context.Context, operator o.Operator, version o.Version, cache *Cache) (string, error) {
+	if version.Git == nil || version.Git.Tag == "" {
+		return "", errors.New("version doesn't reference a Git tag")
+	}
+
+	source, auth, err := gitSource(operator, version.Git.Source)
+	if err != nil {
+		return "", err
+	}
+
+	return cache.git.Commit(ctx, source.URL, auth, git.TagRef(version.Git.Tag))
+}
+
+// GitTags returns the names of the tags of the Git source 'sourceName' of
+// 'operator'. Repositories are accessed through 'cache'.
+func GitTags(ctx context.Context, operator o.Operator, sourceName string, cache *Cache) ([]string, error) {
+	source, auth, err := gitSource(operator, sourceName)
+	if err != nil {
+		return nil, err
+	}
+
+	return cache.git.Tags(ctx, source.URL, auth)
+}
+
+// gitSource returns a Git source of 'operator' and its credentials.
+func gitSource(operator o.Operator, name string) (*o.GitSource, git.Auth, error) {
+	source := findSource(operator.GitSources, name)
+	if source == nil {
+		return nil, git.Auth{}, fmt.Errorf("unknown git source %q", name)
+	}
+
+	auth, err := gitAuth(afero.NewOsFs(), source.Auth)
+	if err != nil {
+		return nil, git.Auth{}, fmt.Errorf("invalid auth of git source %q: %v", source.Name, err)
+	}
+
+	return source, auth, nil
+}
+
 func findSource(sources []o.GitSource, name string) *o.GitSource {
 	for _, source := range sources {
 		if source.Name == name {
@@ -49,3 +239,62 @@ func findSource(sources []o.GitSource, name string) *o.GitSource {
 
 	return nil
 }
+
+// Package resolves the operator package referenced by 'version' and extracts
+// its version information. Packages of Git references also record the SHA of
+// the commit they have been checked out from.
+// Callers are responsible for removing temporary files of the package by
+// running the returned remover function.
+func Package(
+	ctx context.Context,
+	operator o.Operator,
+	version o.Version,
+	cache *Cache,
+) (repo.Package, func() error, error) {
+	operatorName := fmt.Sprintf("%s-%s", operator.Name, version.Version())
+
+	resolver, err := New(operator, version, cache)
+	if err != nil {
+		return repo.Package{}, nil, fmt.Errorf("failed to resolve operator %q: %v", operatorName, err)
+	}
+
+	var (
+		pkgFs   afero.Fs
+		remover func() error
+		commit  string
+	)
+
+	// Git resolvers report the commit a tag or branch has been resolved to.
+	if gitResolver, ok := resolver.(git.Resolver); ok {
+		pkgFs, remover, commit, err = gitResolver.ResolveCommit(ctx)
+	} else {
+		pkgFs, remover, err = resolver.Resolve(ctx)
+	}
+
+	if err != nil {
+		return repo.Package{}, nil, fmt.Errorf("failed to resolve operator %q: %v", operatorName, err)
+	}
+
+	// The package resolver created a temporary directory for the package file system.
+	// The caller removes it once the package is no longer needed.
+	pkgRemover := func() error {
+		if err := remover(); err != nil {
+			return fmt.Errorf("failed to remove temporary directory of operator %q: %v", operatorName, err)
+		}
+
+		return nil
+	}
+
+	pkg, err := repo.NewPackage(pkgFs)
+	if err != nil {
+		if rerr := pkgRemover(); rerr != nil {
+			log.WithError(rerr).Warn("Failed to clean up operator package")
+		}
+
+		return repo.Package{}, nil, fmt.Errorf("failed to extract package version of operator %q: %v", operatorName, err)
+	}
+
+	pkg.Commit = commit
+
+	return pkg, pkgRemover, nil
+}
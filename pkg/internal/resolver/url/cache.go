@@ -0,0 +1,144 @@
+package url
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"path/filepath"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/afero"
+)
+
+// Cache stores downloaded archives in a directory, together with the
+// 'ETag' and 'Last-Modified' headers of their HTTP responses. Cached archives
+// are revalidated with conditional requests.
+type Cache struct {
+	fs afero.Fs
+}
+
+// archiveSuffix is the suffix of cached archives. Archives can be in any
+// format supported by 'archive.Extract', not only gzipped tarballs.
+const archiveSuffix = ".archive"
+
+// cacheEntry is the metadata stored alongside a cached tarball.
+type cacheEntry struct {
+	URL          string `json:"url"`
+	ETag         string `json:"etag,omitempty"`
+	LastModified string `json:"lastModified,omitempty"`
+}
+
+// NewCache creates a new cache storing tarballs in 'dir'.
+func NewCache(dir string) (*Cache, error) {
+	fs := afero.NewOsFs()
+
+	if err := fs.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create cache directory %q: %v", dir, err)
+	}
+
+	return &Cache{
+		fs: afero.NewBasePathFs(fs, dir),
+	}, nil
+}
+
+// revalidate adds conditional headers to 'req' if a tarball for its URL is
+// cached.
+func (c *Cache) revalidate(req *http.Request) {
+	entry, ok := c.entry(req.URL.String())
+	if !ok {
+		return
+	}
+
+	if entry.ETag != "" {
+		req.Header.Set("If-None-Match", entry.ETag)
+	}
+
+	if entry.LastModified != "" {
+		req.Header.Set("If-Modified-Since", entry.LastModified)
+	}
+}
+
+// load returns the cached tarball of 'url'.
+func (c *Cache) load(url string) ([]byte, error) {
+	return afero.ReadFile(c.fs, c.key(url)+archiveSuffix)
+}
+
+// store caches the tarball of 'url' if the response allows revalidation.
+func (c *Cache) store(url string, resp *http.Response, tarball []byte) error {
+	entry := cacheEntry{
+		URL:          url,
+		ETag:         resp.Header.Get("ETag"),
+		LastModified: resp.Header.Get("Last-Modified"),
+	}
+
+	if entry.ETag == "" && entry.LastModified == "" {
+		log.WithField("url", url).
+			Debug("Response can't be revalidated, not caching tarball")
+
+		return nil
+	}
+
+	metadata, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+
+	key := c.key(url)
+
+	// The tarball is written first. An entry without tarball would
+	// result in conditional requests for a tarball that isn't cached.
+	if err := writeFileAtomic(c.fs, key+archiveSuffix, tarball); err != nil {
+		return err
+	}
+
+	return writeFileAtomic(c.fs, key+".json", metadata)
+}
+
+func (c *Cache) entry(url string) (cacheEntry, bool) {
+	key := c.key(url)
+
+	if exists, err := afero.Exists(c.fs, key+archiveSuffix); err != nil || !exists {
+		return cacheEntry{}, false
+	}
+
+	metadata, err := afero.ReadFile(c.fs, key+".json")
+	if err != nil {
+		return cacheEntry{}, false
+	}
+
+	var entry cacheEntry
+
+	if err := json.Unmarshal(metadata, &entry); err != nil || entry.URL != url {
+		return cacheEntry{}, false
+	}
+
+	return entry, true
+}
+
+func (c *Cache) key(url string) string {
+	sum := sha256.Sum256([]byte(url))
+	return hex.EncodeToString(sum[:])
+}
+
+func writeFileAtomic(fs afero.Fs, filename string, data []byte) error {
+	f, err := afero.TempFile(fs, filepath.Dir(filename), ".tmp-")
+	if err != nil {
+		return err
+	}
+
+	if _, err := f.Write(data); err != nil {
+		_ = f.Close()
+		_ = fs.Remove(f.Name())
+
+		return err
+	}
+
+	if err := f.Close(); err != nil {
+		_ = fs.Remove(f.Name())
+		return err
+	}
+
+	return fs.Rename(f.Name(), filename)
+}
@@ -0,0 +1,45 @@
+package url
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/kudobuilder/kitt/pkg/internal/resolver/archive"
+)
+
+func TestCacheRevalidation(t *testing.T) {
+	var downloads, revalidations int
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			revalidations++
+
+			w.WriteHeader(http.StatusNotModified)
+
+			return
+		}
+
+		downloads++
+
+		w.Header().Set("ETag", `"v1"`)
+		_, _ = w.Write([]byte("tarball"))
+	}))
+	defer server.Close()
+
+	cache := &Cache{fs: afero.NewMemMapFs()}
+	resolver := NewResolver(server.URL+"/foo.tgz", "", "", Auth{}, newTestClient(t), archive.DefaultLimits(), cache)
+
+	for i := 0; i < 3; i++ {
+		tarball, err := resolver.download(context.Background())
+		assert.NoError(t, err)
+		assert.Equal(t, []byte("tarball"), tarball)
+	}
+
+	assert.Equal(t, 1, downloads)
+	assert.Equal(t, 2, revalidations)
+}
@@ -0,0 +1,249 @@
+package url
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	neturl "net/url"
+	"sync/atomic"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/afero"
+)
+
+// ClientOptions configure the HTTP client of URL resolvers.
+type ClientOptions struct {
+	// ConnectTimeout limits establishing a connection, including the TLS
+	// handshake. Zero means no limit.
+	ConnectTimeout time.Duration
+
+	// ReadTimeout limits waiting for the response headers and for each read
+	// of the response body. Zero means no limit.
+	ReadTimeout time.Duration
+
+	// Retries is the number of times a request is retried after a network
+	// error or a server error response.
+	Retries int
+
+	// Proxy is the URL of an HTTP proxy. If empty, the proxy is configured by
+	// the environment variables "HTTPS_PROXY", "HTTP_PROXY" and "NO_PROXY".
+	Proxy string
+
+	// CABundle is the path of a file with PEM encoded certificates, which are
+	// trusted in addition to the system's certificates.
+	CABundle string
+}
+
+// Client downloads tarballs. Requests are retried with an exponential backoff
+// after network errors and server error responses.
+type Client struct {
+	http *http.Client
+
+	readTimeout time.Duration
+	retries     int
+
+	// Backoff before the first retry, doubled for each further retry.
+	backoff time.Duration
+}
+
+// NewClient creates a new client configured by 'options'.
+func NewClient(options ClientOptions) (*Client, error) {
+	client, err := NewHTTPClient(options)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Client{
+		http:        client,
+		readTimeout: options.ReadTimeout,
+		retries:     options.Retries,
+		backoff:     time.Second,
+	}, nil
+}
+
+// NewHTTPClient creates an HTTP client with the connect timeout, proxy and CA
+// bundle of 'options'. 'ReadTimeout' only limits waiting for response headers,
+// reads of response bodies and retries are limited by 'Client'.
+func NewHTTPClient(options ClientOptions) (*http.Client, error) {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+
+	transport.DialContext = (&net.Dialer{
+		Timeout:   options.ConnectTimeout,
+		KeepAlive: 30 * time.Second,
+	}).DialContext
+	transport.TLSHandshakeTimeout = options.ConnectTimeout
+	transport.ResponseHeaderTimeout = options.ReadTimeout
+
+	if options.Proxy != "" {
+		proxy, err := neturl.Parse(options.Proxy)
+		if err != nil {
+			return nil, fmt.Errorf("invalid proxy URL %q: %v", options.Proxy, err)
+		}
+
+		transport.Proxy = http.ProxyURL(proxy)
+	}
+
+	if options.CABundle != "" {
+		pool, err := certPool(afero.NewOsFs(), options.CABundle)
+		if err != nil {
+			return nil, err
+		}
+
+		transport.TLSClientConfig = &tls.Config{RootCAs: pool, MinVersion: tls.VersionTLS12}
+	}
+
+	return &http.Client{Transport: transport, CheckRedirect: checkRedirect}, nil
+}
+
+// maxRedirects is the number of redirects followed for a request, the same
+// limit as the default of 'http.Client'.
+const maxRedirects = 10
+
+// checkRedirect removes all headers of requests redirected to another host.
+// Only "Authorization" and cookies are removed by 'http.Client', but
+// configured headers may contain secrets as well, e.g. API keys of artifact
+// servers redirecting to a storage service.
+func checkRedirect(req *http.Request, via []*http.Request) error {
+	if len(via) >= maxRedirects {
+		return fmt.Errorf("stopped after %d redirects", maxRedirects)
+	}
+
+	if req.URL.Host != via[0].URL.Host {
+		req.Header = http.Header{}
+	}
+
+	return nil
+}
+
+// certPool returns the system's certificates together with the certificates
+// of the PEM file 'path'.
+func certPool(fs afero.Fs, path string) (*x509.CertPool, error) {
+	pool, err := x509.SystemCertPool()
+	if err != nil {
+		pool = x509.NewCertPool()
+	}
+
+	bundle, err := afero.ReadFile(fs, path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CA bundle: %v", err)
+	}
+
+	if !pool.AppendCertsFromPEM(bundle) {
+		return nil, fmt.Errorf("no certificates found in CA bundle %q", path)
+	}
+
+	return pool, nil
+}
+
+// retryableError is an error of an attempt that is retried.
+type retryableError struct {
+	err error
+}
+
+func (e retryableError) Error() string {
+	return e.err.Error()
+}
+
+// get sends a GET request for 'url', prepared by 'prepare', and returns the
+// response together with its body. Responses other than 2xx and
+// "304 Not Modified" are returned as errors.
+func (c *Client) get(ctx context.Context, url string, prepare func(*http.Request)) (*http.Response, []byte, error) {
+	backoff := c.backoff
+
+	for attempt := 0; ; attempt++ {
+		resp, body, err := c.attempt(ctx, url, prepare)
+
+		var retryable retryableError
+		if !errors.As(err, &retryable) || attempt >= c.retries || ctx.Err() != nil {
+			return resp, body, err
+		}
+
+		log.WithField("url", url).
+			WithField("backoff", backoff).
+			WithError(err).
+			Warn("HTTP request failed, retrying")
+
+		select {
+		case <-ctx.Done():
+			return nil, nil, ctx.Err()
+		case <-time.After(backoff):
+		}
+
+		backoff *= 2
+	}
+}
+
+func (c *Client) attempt(ctx context.Context, url string, prepare func(*http.Request)) (*http.Response, []byte, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create HTTP request for %q: %v", url, err)
+	}
+
+	prepare(req)
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, nil, retryableError{fmt.Errorf("failed to get HTTP response for %q: %v", url, err)}
+	}
+
+	defer resp.Body.Close() //nolint:errcheck
+
+	switch {
+	case resp.StatusCode >= 500:
+		return nil, nil, retryableError{fmt.Errorf("unexpected HTTP status %q for %q", resp.Status, url)}
+	case resp.StatusCode == http.StatusNotModified:
+		return resp, nil, nil
+	case resp.StatusCode < 200 || resp.StatusCode >= 300:
+		return nil, nil, fmt.Errorf("unexpected HTTP status %q for %q", resp.Status, url)
+	}
+
+	var body io.Reader = resp.Body
+
+	var stalled int32
+
+	// The request is canceled if reading the body stalls.
+	if c.readTimeout > 0 {
+		timer := time.AfterFunc(c.readTimeout, func() {
+			atomic.StoreInt32(&stalled, 1)
+			cancel()
+		})
+		defer timer.Stop()
+
+		body = &stallReader{r: resp.Body, timer: timer, timeout: c.readTimeout}
+	}
+
+	content, err := ioutil.ReadAll(body)
+	if err != nil {
+		if atomic.LoadInt32(&stalled) == 1 {
+			err = fmt.Errorf("no data received for %s", c.readTimeout)
+		}
+
+		return nil, nil, retryableError{fmt.Errorf("failed to read HTTP response for %q: %v", url, err)}
+	}
+
+	return resp, content, nil
+}
+
+// stallReader resets a timer after each read.
+type stallReader struct {
+	r       io.Reader
+	timer   *time.Timer
+	timeout time.Duration
+}
+
+func (r *stallReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	r.timer.Reset(r.timeout)
+
+	return n, err
+}
@@ -0,0 +1,142 @@
+package url
+
+import (
+	"context"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+)
+
+// newTestClient creates a client which retries twice without waiting long.
+func newTestClient(t *testing.T) *Client {
+	client, err := NewClient(ClientOptions{ReadTimeout: 100 * time.Millisecond, Retries: 2})
+	assert.NoError(t, err)
+
+	client.backoff = time.Millisecond
+
+	return client
+}
+
+func TestClientRetries(t *testing.T) {
+	tests := []struct {
+		name      string
+		statuses  []int
+		requests  int
+		expectErr string
+	}{
+		{
+			name:     "success",
+			statuses: []int{http.StatusOK},
+			requests: 1,
+		},
+		{
+			name:     "server errors are retried",
+			statuses: []int{http.StatusServiceUnavailable, http.StatusBadGateway, http.StatusOK},
+			requests: 3,
+		},
+		{
+			name:      "retries are exhausted",
+			statuses:  []int{http.StatusInternalServerError},
+			requests:  3,
+			expectErr: `unexpected HTTP status "500 Internal Server Error" for "%s"`,
+		},
+		{
+			name:      "client errors aren't retried",
+			statuses:  []int{http.StatusNotFound},
+			requests:  1,
+			expectErr: `unexpected HTTP status "404 Not Found" for "%s"`,
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+
+		t.Run(test.name, func(t *testing.T) {
+			requests := 0
+
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				status := test.statuses[len(test.statuses)-1]
+				if requests < len(test.statuses) {
+					status = test.statuses[requests]
+				}
+
+				requests++
+
+				w.WriteHeader(status)
+				_, _ = w.Write([]byte("tarball"))
+			}))
+			defer server.Close()
+
+			url := server.URL + "/foo.tgz"
+
+			_, body, err := newTestClient(t).get(context.Background(), url, func(*http.Request) {})
+
+			if test.expectErr != "" {
+				assert.EqualError(t, err, fmt.Sprintf(test.expectErr, url))
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, []byte("tarball"), body)
+			}
+
+			assert.Equal(t, test.requests, requests)
+		})
+	}
+}
+
+func TestClientReadTimeout(t *testing.T) {
+	done := make(chan struct{})
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Length", "14")
+		_, _ = w.Write([]byte("tar"))
+		w.(http.Flusher).Flush()
+
+		// The rest of the body never arrives.
+		select {
+		case <-r.Context().Done():
+		case <-done:
+		}
+	}))
+	defer server.Close()
+	defer close(done)
+
+	url := server.URL + "/foo.tgz"
+
+	_, _, err := newTestClient(t).get(context.Background(), url, func(*http.Request) {})
+	assert.EqualError(t, err, `failed to read HTTP response for "`+url+`": no data received for 100ms`)
+}
+
+func TestClientCABundle(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("tarball"))
+	}))
+	defer server.Close()
+
+	url := server.URL + "/foo.tgz"
+
+	// The certificate of the test server isn't trusted by default.
+	_, _, err := newTestClient(t).get(context.Background(), url, func(*http.Request) {})
+	assert.Error(t, err)
+
+	bundle := filepath.Join(t.TempDir(), "ca.pem")
+	cert := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+
+	assert.NoError(t, afero.WriteFile(afero.NewOsFs(), bundle, cert, 0644))
+
+	client, err := NewClient(ClientOptions{CABundle: bundle})
+	assert.NoError(t, err)
+
+	_, body, err := client.get(context.Background(), url, func(*http.Request) {})
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("tarball"), body)
+
+	_, err = NewClient(ClientOptions{CABundle: filepath.Join(t.TempDir(), "missing.pem")})
+	assert.Error(t, err)
+}
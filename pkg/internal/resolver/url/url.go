@@ -1,115 +1,169 @@
 package url
 
 import (
-	"archive/tar"
 	"bytes"
-	"compress/gzip"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
-	"io"
-	"io/ioutil"
 	"net/http"
-	"path/filepath"
+	"strings"
 
 	log "github.com/sirupsen/logrus"
 	"github.com/spf13/afero"
+
+	"github.com/kudobuilder/kitt/pkg/internal/resolver/archive"
 )
 
-// Resolver resolves operator package from URLs pointing to package tarballs.
+// Resolver resolves operator package from URLs pointing to package archives.
 type Resolver struct {
 	URL string
+
+	// SHA256 is the expected checksum of the archive, optional.
+	SHA256 string
+
+	// Subdirectory of the archive containing the package, optional.
+	Subdirectory string
+
+	Auth Auth
+
+	client *Client
+	limits archive.Limits
+	cache  *Cache
+}
+
+// Auth holds the credentials and headers of requests.
+type Auth struct {
+	// Username and Password for HTTP basic authentication.
+	Username string
+	Password string
+
+	// BearerToken takes precedence over basic authentication.
+	BearerToken string
+
+	Header http.Header
 }
 
-// NewResolver creates a new Resolver for a URL.
-func NewResolver(url string) Resolver {
+// NewResolver creates a new Resolver for a URL, requested with the credentials
+// and headers of 'auth'.
+// If 'sha256' isn't empty, downloaded archives have to match this hex encoded
+// checksum. If 'subdirectory' isn't empty, the package is read from this
+// directory of the archive.
+// Archives are downloaded by 'client' and extracted within 'limits'. If 'cache'
+// isn't nil, downloaded archives are cached and only downloaded again if they
+// changed.
+func NewResolver(
+	url, sha256, subdirectory string,
+	auth Auth,
+	client *Client,
+	limits archive.Limits,
+	cache *Cache,
+) Resolver {
 	return Resolver{
-		URL: url,
+		URL:          url,
+		SHA256:       sha256,
+		Subdirectory: subdirectory,
+		Auth:         auth,
+		client:       client,
+		limits:       limits,
+		cache:        cache,
 	}
 }
 
-// Resolve downloads an operator package tarball and extracts it into a file system.
-func (r Resolver) Resolve(ctx context.Context) (fs afero.Fs, rem func() error, err error) {
-	req, err := http.NewRequestWithContext(ctx, "GET", r.URL, nil)
+// Resolve downloads an operator package archive and extracts it into a file
+// system.
+func (r Resolver) Resolve(ctx context.Context) (afero.Fs, func() error, error) {
+	tarball, err := r.download(ctx)
 	if err != nil {
-		return nil, nil, fmt.Errorf("failed to create HTTP request for %q: %v", r.URL, err)
+		return nil, nil, err
 	}
 
-	log.WithField("url", r.URL).
-		Info("Downloading operator tarball")
+	if err := r.verify(tarball); err != nil {
+		return nil, nil, err
+	}
 
-	resp, err := http.DefaultClient.Do(req)
+	fs, err := archive.Extract(tarball, r.Subdirectory, r.limits)
 	if err != nil {
-		return nil, nil, fmt.Errorf("failed to get HTTP response for %q: %v", r.URL, err)
+		return nil, nil, err
 	}
 
-	tarball, err := ioutil.ReadAll(resp.Body)
-	if err != nil {
-		return nil, nil, fmt.Errorf("failed to read HTTP response for %q: %v", r.URL, err)
+	remover := func() error {
+		// Nothing to clean up, because we're providing the file system in memory.
+		return nil
 	}
 
-	fs = afero.NewMemMapFs()
+	return fs, remover, nil
+}
 
-	// Using 'MemMapFs' with the default base path causes all kinds of trouble.
-	// To avoid potential issues, all files are created in directories and
-	// 'BasePathFs' is used to point to a different base path.
-	operatorDir := filepath.Join(string(filepath.Separator), "operator")
+// verify checks that the checksum of 'tarball' matches the expected checksum,
+// if there is one.
+func (r Resolver) verify(tarball []byte) error {
+	if r.SHA256 == "" {
+		return nil
+	}
 
-	if err := fs.Mkdir(operatorDir, 0755); err != nil {
-		return nil, nil, fmt.Errorf("failed to create operator directory: %v", err)
+	expected, err := hex.DecodeString(r.SHA256)
+	if err != nil || len(expected) != sha256.Size {
+		return fmt.Errorf("invalid sha256 checksum %q for %q", r.SHA256, r.URL)
 	}
 
-	gzr, err := gzip.NewReader(bytes.NewBuffer(tarball))
-	if err != nil {
-		return nil, nil, fmt.Errorf("failed to unzip tarball: %v", err)
+	actual := sha256.Sum256(tarball)
+	if !bytes.Equal(expected, actual[:]) {
+		return fmt.Errorf("sha256 checksum mismatch for %q: expected %s, got %s",
+			r.URL, strings.ToLower(r.SHA256), hex.EncodeToString(actual[:]))
 	}
 
-	defer func() {
-		if cerr := gzr.Close(); cerr != nil {
-			err = fmt.Errorf("failed to close tarball: %v", cerr)
-		}
-	}()
+	return nil
+}
 
-	tr := tar.NewReader(gzr)
+func (r Resolver) download(ctx context.Context) ([]byte, error) {
+	log.WithField("url", r.URL).
+		Info("Downloading operator tarball")
 
-	for {
-		hdr, err := tr.Next()
-		if err == io.EOF {
-			break
+	resp, tarball, err := r.client.get(ctx, r.URL, func(req *http.Request) {
+		r.Auth.apply(req)
+
+		if r.cache != nil {
+			r.cache.revalidate(req)
 		}
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if r.cache != nil && resp.StatusCode == http.StatusNotModified {
+		log.WithField("url", r.URL).
+			Info("Using cached operator tarball")
 
+		tarball, err := r.cache.load(r.URL)
 		if err != nil {
-			return nil, nil, fmt.Errorf("failed to read tarball entry: %v", err)
+			return nil, fmt.Errorf("failed to read cached tarball for %q: %v", r.URL, err)
 		}
 
-		switch hdr.Typeflag {
-		case tar.TypeReg:
-			buf, err := ioutil.ReadAll(tr)
-			if err != nil {
-				return nil, nil, fmt.Errorf("failed to extract tarball entry: %v", err)
-			}
-
-			filename := filepath.Join(operatorDir, hdr.Name)
-
-			// 'WriteFile' won't create directories, let's do this here instead.
-			// 'MkdirAll' won't fail if directories already exists which makes
-			// it safe to call all the time.
-			dir := filepath.Dir(filename)
-			if err := fs.MkdirAll(dir, 0755); err != nil {
-				return nil, nil, fmt.Errorf("failed to create operator directory %q: %v", dir, err)
-			}
-
-			if err := afero.WriteFile(fs, filename, buf, hdr.FileInfo().Mode()); err != nil {
-				return nil, nil, fmt.Errorf("failed to write operator file %q: %v", filename, err)
-			}
-		default:
-			continue
+		return tarball, nil
+	}
+
+	if r.cache != nil && resp.StatusCode == http.StatusOK {
+		if err := r.cache.store(r.URL, resp, tarball); err != nil {
+			return nil, fmt.Errorf("failed to cache tarball for %q: %v", r.URL, err)
 		}
 	}
 
-	rem = func() error {
-		// Nothing to clean up, because we're providing the file system in memory.
-		return nil
+	return tarball, nil
+}
+
+// apply sets the credentials and headers of a request.
+func (a Auth) apply(req *http.Request) {
+	for name, values := range a.Header {
+		for _, value := range values {
+			req.Header.Add(name, value)
+		}
 	}
 
-	return afero.NewBasePathFs(fs, operatorDir), rem, nil
+	switch {
+	case a.BearerToken != "":
+		req.Header.Set("Authorization", "Bearer "+a.BearerToken)
+	case a.Username != "" || a.Password != "":
+		req.SetBasicAuth(a.Username, a.Password)
+	}
 }
@@ -0,0 +1,180 @@
+package url
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/kudobuilder/kitt/pkg/internal/resolver/archive"
+)
+
+func TestDownloadAuth(t *testing.T) {
+	tests := []struct {
+		name          string
+		auth          Auth
+		authorization string
+	}{
+		{
+			name:          "basic auth",
+			auth:          Auth{Username: "kitt", Password: "secret"},
+			authorization: "Basic a2l0dDpzZWNyZXQ=",
+		},
+		{
+			name:          "bearer token",
+			auth:          Auth{Username: "kitt", Password: "secret", BearerToken: "token"},
+			authorization: "Bearer token",
+		},
+		{
+			name: "no auth",
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+
+		t.Run(test.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				assert.Equal(t, test.authorization, r.Header.Get("Authorization"))
+				assert.Equal(t, "application/octet-stream", r.Header.Get("Accept"))
+
+				_, _ = w.Write([]byte("tarball"))
+			}))
+			defer server.Close()
+
+			test.auth.Header = http.Header{"Accept": []string{"application/octet-stream"}}
+
+			resolver := NewResolver(server.URL+"/foo.tgz", "", "", test.auth, newTestClient(t), archive.DefaultLimits(), nil)
+
+			tarball, err := resolver.download(context.Background())
+			assert.NoError(t, err)
+			assert.Equal(t, []byte("tarball"), tarball)
+		})
+	}
+}
+
+func TestDownloadAuthRedirect(t *testing.T) {
+	storage := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Empty(t, r.Header.Get("Authorization"))
+		assert.Empty(t, r.Header.Get("X-Api-Key"))
+
+		_, _ = w.Write([]byte("tarball"))
+	}))
+	defer storage.Close()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/foo.tgz":
+			// Redirects on the same host keep the headers.
+			http.Redirect(w, r, "/redirect/foo.tgz", http.StatusFound)
+		default:
+			assert.Equal(t, "Bearer token", r.Header.Get("Authorization"))
+			assert.Equal(t, "key", r.Header.Get("X-Api-Key"))
+
+			http.Redirect(w, r, storage.URL+"/foo.tgz", http.StatusFound)
+		}
+	}))
+	defer server.Close()
+
+	auth := Auth{BearerToken: "token", Header: http.Header{"X-Api-Key": []string{"key"}}}
+
+	resolver := NewResolver(server.URL+"/foo.tgz", "", "", auth, newTestClient(t), archive.DefaultLimits(), nil)
+
+	tarball, err := resolver.download(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("tarball"), tarball)
+}
+
+func TestVerify(t *testing.T) {
+	// SHA-256 of "tarball".
+	checksum := "db4b4d0d1cb480bf9aeea253771c00febe627f236765fa37d6a5614f079a3aa0"
+
+	tests := []struct {
+		name      string
+		sha256    string
+		expectErr string
+	}{
+		{
+			name: "not pinned",
+		},
+		{
+			name:   "matching checksum",
+			sha256: checksum,
+		},
+		{
+			name:   "upper case checksum",
+			sha256: strings.ToUpper(checksum),
+		},
+		{
+			name:   "mismatching checksum",
+			sha256: strings.Repeat("0", 64),
+			expectErr: `sha256 checksum mismatch for "https://example.org/foo.tgz": expected ` +
+				strings.Repeat("0", 64) + ", got " + checksum,
+		},
+		{
+			name:      "invalid checksum",
+			sha256:    "abc",
+			expectErr: `invalid sha256 checksum "abc" for "https://example.org/foo.tgz"`,
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+
+		t.Run(test.name, func(t *testing.T) {
+			resolver := NewResolver("https://example.org/foo.tgz", test.sha256, "", Auth{}, nil, archive.DefaultLimits(), nil)
+
+			err := resolver.verify([]byte("tarball"))
+
+			if test.expectErr != "" {
+				assert.EqualError(t, err, test.expectErr)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestResolveSubdirectory(t *testing.T) {
+	var buf bytes.Buffer
+
+	zw := zip.NewWriter(&buf)
+
+	for name, content := range map[string]string{
+		"repo-1.0.0/README.md":              "repo",
+		"repo-1.0.0/operator/operator.yaml": "name: foo",
+	} {
+		w, err := zw.Create(name)
+		assert.NoError(t, err)
+
+		_, err = w.Write([]byte(content))
+		assert.NoError(t, err)
+	}
+
+	assert.NoError(t, zw.Close())
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write(buf.Bytes())
+	}))
+	defer server.Close()
+
+	url := server.URL + "/repo-1.0.0.zip"
+
+	fs, _, err := NewResolver(url, "", "operator", Auth{}, newTestClient(t), archive.DefaultLimits(), nil).
+		Resolve(context.Background())
+	assert.NoError(t, err)
+
+	content, err := afero.ReadFile(fs, "operator.yaml")
+	assert.NoError(t, err)
+	assert.Equal(t, "name: foo", string(content))
+
+	_, _, err = NewResolver(url, "", "missing", Auth{}, newTestClient(t), archive.DefaultLimits(), nil).
+		Resolve(context.Background())
+	assert.EqualError(t, err, `subdirectory "missing" not found in archive`)
+}
@@ -4,28 +4,48 @@ import (
 	"fmt"
 )
 
+// Rules identify the check that reported an issue.
+const (
+	RuleOperatorVersionSemver   = "operator-version-semver"
+	RuleOperatorVersionMismatch = "operator-version-mismatch"
+	RuleAppVersionSemver        = "app-version-semver"
+	RuleAppVersionMismatch      = "app-version-mismatch"
+	RuleAppVersionNotSet        = "app-version-not-set"
+	RuleAppVersionNotProvided   = "app-version-not-provided"
+	RulePackageVerify           = "package-verify"
+	RuleURLUnpinned             = "url-unpinned"
+	RuleGitBranch               = "git-branch"
+	RuleResolve                 = "resolve"
+)
+
 // Result is a validation result consisting of a list of warnings and errors.
 type Result struct {
-	Warnings []string
-	Errors   []string
+	Warnings []Issue
+	Errors   []Issue
+}
+
+// Issue is a single warning or error reported by a rule.
+type Issue struct {
+	Rule    string
+	Message string
 }
 
 // AddWarning adds a warning to a validation result.
-func (r *Result) AddWarning(warning string) {
-	r.Warnings = append(r.Warnings, warning)
+func (r *Result) AddWarning(rule string, warning string) {
+	r.Warnings = append(r.Warnings, Issue{Rule: rule, Message: warning})
 }
 
 // AddWarningf adds a formatted warning to a validation result.
-func (r *Result) AddWarningf(warning string, a ...interface{}) {
-	r.Warnings = append(r.Warnings, fmt.Sprintf(warning, a...))
+func (r *Result) AddWarningf(rule string, warning string, a ...interface{}) {
+	r.AddWarning(rule, fmt.Sprintf(warning, a...))
 }
 
 // AddError adds an error to a validation result.
-func (r *Result) AddError(error string) {
-	r.Errors = append(r.Errors, error)
+func (r *Result) AddError(rule string, error string) {
+	r.Errors = append(r.Errors, Issue{Rule: rule, Message: error})
 }
 
 // AddErrorf adds a formatted error to a validation result.
-func (r *Result) AddErrorf(error string, a ...interface{}) {
-	r.Errors = append(r.Errors, fmt.Sprintf(error, a...))
+func (r *Result) AddErrorf(rule string, error string, a ...interface{}) {
+	r.AddError(rule, fmt.Sprintf(error, a...))
 }
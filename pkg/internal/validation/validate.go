@@ -16,6 +16,7 @@ func Validate(operator operator.Operator, version operator.Version, pkg repo.Pac
 	result := Result{}
 
 	validateVersion(version, pkg, &result)
+	validateReference(version, &result)
 	validateVerify(pkg, &result)
 
 	return result
@@ -24,9 +25,10 @@ func Validate(operator operator.Operator, version operator.Version, pkg repo.Pac
 func validateVersion(version operator.Version, pkg repo.Package, result *Result) {
 	operatorVersion, err := semver.NewVersion(version.OperatorVersion)
 	if err != nil {
-		result.AddError("operatorVersion isn't semver")
+		result.AddError(RuleOperatorVersionSemver, "operatorVersion isn't semver")
 	} else if !operatorVersion.Equal(&pkg.OperatorVersion) {
 		result.AddWarningf(
+			RuleOperatorVersionMismatch,
 			"operatorVersion %q doesn't match operatorVersion %q in operator package",
 			operatorVersion,
 			pkg.OperatorVersion)
@@ -35,19 +37,33 @@ func validateVersion(version operator.Version, pkg repo.Package, result *Result)
 	if version.AppVersion != "" {
 		appVersion, err := semver.NewVersion(version.AppVersion)
 		if err != nil {
-			result.AddError("appVersion isn't semver")
+			result.AddError(RuleAppVersionSemver, "appVersion isn't semver")
 		} else {
 			if pkg.AppVersion == nil {
-				result.AddWarning("appVersion provided but not set in operator package")
+				result.AddWarning(RuleAppVersionNotSet, "appVersion provided but not set in operator package")
 			} else if !appVersion.Equal(pkg.AppVersion) {
 				result.AddWarningf(
+					RuleAppVersionMismatch,
 					"appVersion %q doesn't match appVersion %q in operator package",
 					appVersion,
 					pkg.AppVersion)
 			}
 		}
 	} else if pkg.AppVersion != nil {
-		result.AddWarning("appVersion not provided but set in operator package")
+		result.AddWarning(RuleAppVersionNotProvided, "appVersion not provided but set in operator package")
+	}
+}
+
+func validateReference(version operator.Version, result *Result) {
+	if version.URL != nil && version.SHA256 == "" {
+		result.AddWarning(RuleURLUnpinned, "url isn't pinned by a sha256 checksum")
+	}
+
+	if version.Git != nil && version.Git.Branch != "" && version.Git.SHA == "" {
+		result.AddWarningf(
+			RuleGitBranch,
+			"git reference uses branch %q, which isn't reproducible",
+			version.Git.Branch)
 	}
 }
 
@@ -62,10 +78,10 @@ func validateVerify(pkg repo.Package, result *Result) {
 	verifyResult := verify.PackageFiles(p.Files)
 
 	for _, warning := range verifyResult.Warnings {
-		result.AddWarning(warning)
+		result.AddWarning(RulePackageVerify, warning)
 	}
 
 	for _, error := range verifyResult.Errors {
-		result.AddError(error)
+		result.AddError(RulePackageVerify, error)
 	}
 }
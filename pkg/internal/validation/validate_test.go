@@ -25,7 +25,7 @@ operatorVersion: "1.0.0"`),
 				OperatorVersion: "next",
 			},
 			result: Result{
-				Errors: []string{"operatorVersion isn't semver"},
+				Errors: []Issue{{Rule: RuleOperatorVersionSemver, Message: "operatorVersion isn't semver"}},
 			},
 		},
 		{
@@ -36,7 +36,10 @@ operatorVersion: "1.0.0"`),
 				OperatorVersion: "1.1.0",
 			},
 			result: Result{
-				Warnings: []string{"operatorVersion \"1.1.0\" doesn't match operatorVersion \"1.0.0\" in operator package"},
+				Warnings: []Issue{{
+					Rule:    RuleOperatorVersionMismatch,
+					Message: "operatorVersion \"1.1.0\" doesn't match operatorVersion \"1.0.0\" in operator package",
+				}},
 			},
 		},
 		{
@@ -49,7 +52,7 @@ appVersion: "1.0.0"`),
 				AppVersion:      "next",
 			},
 			result: Result{
-				Errors: []string{"appVersion isn't semver"},
+				Errors: []Issue{{Rule: RuleAppVersionSemver, Message: "appVersion isn't semver"}},
 			},
 		},
 		{
@@ -62,7 +65,10 @@ appVersion: "1.0.0"`),
 				AppVersion:      "1.1.0",
 			},
 			result: Result{
-				Warnings: []string{"appVersion \"1.1.0\" doesn't match appVersion \"1.0.0\" in operator package"},
+				Warnings: []Issue{{
+					Rule:    RuleAppVersionMismatch,
+					Message: "appVersion \"1.1.0\" doesn't match appVersion \"1.0.0\" in operator package",
+				}},
 			},
 		},
 		{
@@ -74,7 +80,10 @@ operatorVersion: "1.0.0"`),
 				AppVersion:      "1.0.0",
 			},
 			result: Result{
-				Warnings: []string{"appVersion provided but not set in operator package"},
+				Warnings: []Issue{{
+					Rule:    RuleAppVersionNotSet,
+					Message: "appVersion provided but not set in operator package",
+				}},
 			},
 		},
 		{
@@ -86,7 +95,10 @@ appVersion: "1.0.0"`),
 				OperatorVersion: "1.0.0",
 			},
 			result: Result{
-				Warnings: []string{"appVersion not provided but set in operator package"},
+				Warnings: []Issue{{
+					Rule:    RuleAppVersionNotProvided,
+					Message: "appVersion not provided but set in operator package",
+				}},
 			},
 		},
 		{
@@ -110,6 +122,59 @@ appVersion: "1.0.0"`),
 	}
 }
 
+func TestValidateReference(t *testing.T) {
+	url := "https://example.org/foo-1.0.0.tgz"
+
+	tests := []struct {
+		name    string
+		version operator.Version
+		result  Result
+	}{
+		{
+			name:    "unpinned URL",
+			version: operator.Version{URL: &url},
+			result: Result{
+				Warnings: []Issue{{Rule: RuleURLUnpinned, Message: "url isn't pinned by a sha256 checksum"}},
+			},
+		},
+		{
+			name: "pinned URL",
+			version: operator.Version{
+				URL:    &url,
+				SHA256: "db4b4d0d1cb480bf9aeea253771c00febe627f236765fa37d6a5614f079a3aa0",
+			},
+			result: Result{},
+		},
+		{
+			name:    "Git reference",
+			version: operator.Version{Git: &operator.Git{Source: "foo", Tag: "v1.0.0"}},
+			result:  Result{},
+		},
+		{
+			name:    "Git branch",
+			version: operator.Version{Git: &operator.Git{Source: "foo", Branch: "main"}},
+			result: Result{
+				Warnings: []Issue{{
+					Rule:    RuleGitBranch,
+					Message: `git reference uses branch "main", which isn't reproducible`,
+				}},
+			},
+		},
+		{
+			name:    "pinned Git branch",
+			version: operator.Version{Git: &operator.Git{Source: "foo", Branch: "main", SHA: "0123456789abcdef"}},
+			result:  Result{},
+		},
+	}
+
+	for _, test := range tests {
+		var result Result
+
+		validateReference(test.version, &result)
+		assert.Equal(t, test.result, result, test.name)
+	}
+}
+
 func createPkg(t *testing.T, operator string) repo.Package {
 	pkgFs := afero.NewMemMapFs()
 
@@ -0,0 +1,14 @@
+// Package loadertest provides operator loaders for tests.
+package loadertest
+
+import (
+	"github.com/kudobuilder/kitt/pkg/internal/apis/operator"
+)
+
+// Operators is an operator loader returning a fixed list of operators.
+type Operators []operator.Operator
+
+// Apply returns the operators.
+func (o Operators) Apply() ([]operator.Operator, error) {
+	return o, nil
+}
@@ -1,10 +1,15 @@
 package loader
 
 import (
+	"context"
 	"fmt"
 
+	"github.com/Masterminds/semver/v3"
+
 	"github.com/kudobuilder/kitt/pkg/internal/apis/operator"
 	"github.com/kudobuilder/kitt/pkg/internal/apis/operator/encode"
+	"github.com/kudobuilder/kitt/pkg/internal/discovery"
+	"github.com/kudobuilder/kitt/pkg/internal/resolver"
 )
 
 // OperatorLoader allows to gather operators from different sources.
@@ -35,3 +40,34 @@ func FromFiles(paths []string) OperatorLoader {
 		return operators, nil
 	})
 }
+
+// WithDiscoveredVersions replaces the version templates of the operators of
+// 'operatorLoader' by the versions discovered from the tags of their Git
+// sources. Versions with an operator version lower than 'since' are left out,
+// unless 'since' is nil. Git sources are accessed through 'cache', which
+// should be the cache that later resolves the versions, so that each source is
+// only retrieved once.
+func WithDiscoveredVersions(
+	ctx context.Context,
+	operatorLoader OperatorLoader,
+	cache *resolver.Cache,
+	since *semver.Version,
+) OperatorLoader {
+	return operatorLoaderAdapter(func() ([]operator.Operator, error) {
+		operators, err := operatorLoader.Apply()
+		if err != nil {
+			return operators, err
+		}
+
+		for i := range operators {
+			discovered, err := discovery.Discover(ctx, operators[i], since, cache)
+			if err != nil {
+				return operators, fmt.Errorf("failed to discover versions of operator %q: %v", operators[i].Name, err)
+			}
+
+			operators[i] = discovered
+		}
+
+		return operators, nil
+	})
+}
@@ -0,0 +1,212 @@
+package lock
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/afero"
+	"gopkg.in/yaml.v3"
+
+	"github.com/kudobuilder/kitt/pkg/internal/apis/operator/encode"
+	"github.com/kudobuilder/kitt/pkg/internal/resolver"
+)
+
+// Lock resolves the commits that the Git tags referenced in the operator
+// reference files 'paths' currently point to, and writes their SHAs back into
+// the files. Resolving a locked reference fails if its tag is moved to another
+// commit later on.
+// Sources are retrieved as configured by 'resolverOptions'. Only the 'sha'
+// fields of the files are changed, their comments and formatting are kept.
+func Lock(ctx context.Context, paths []string, resolverOptions resolver.Options) (err error) {
+	cache, err := resolver.NewCache(resolverOptions)
+	if err != nil {
+		return fmt.Errorf("failed to create resolver cache: %v", err)
+	}
+
+	defer func() {
+		if rerr := cache.Remove(); rerr != nil && err == nil {
+			err = fmt.Errorf("failed to remove resolver cache: %v", rerr)
+		}
+	}()
+
+	fs := afero.NewOsFs()
+
+	for _, path := range paths {
+		if err := lockFile(ctx, fs, path, cache); err != nil {
+			return fmt.Errorf("failed to lock %q: %v", path, err)
+		}
+	}
+
+	return nil
+}
+
+func lockFile(ctx context.Context, fs afero.Fs, path string, cache *resolver.Cache) error {
+	operator, err := encode.FromFile(path)
+	if err != nil {
+		return err
+	}
+
+	// SHAs of the locked versions by their index.
+	shas := map[int]string{}
+
+	for i, version := range operator.Versions {
+		if version.Git == nil || version.Git.Tag == "" {
+			continue
+		}
+
+		commit, err := resolver.GitCommit(ctx, operator, version, cache)
+		if err != nil {
+			return fmt.Errorf("failed to resolve tag %q of operator %q: %v", version.Git.Tag, operator.Name, err)
+		}
+
+		logger := log.WithField("operator", operator.Name).
+			WithField("version", version.Version()).
+			WithField("tag", version.Git.Tag).
+			WithField("sha", commit)
+
+		switch version.Git.SHA {
+		case commit:
+			logger.Debug("Git tag is already locked")
+			continue
+		case "":
+			logger.Info("Locking Git tag")
+		default:
+			logger.WithField("previous", version.Git.SHA).
+				Warn("Git tag points to another commit than the locked SHA, locking it again")
+		}
+
+		shas[i] = commit
+	}
+
+	if len(shas) == 0 {
+		return nil
+	}
+
+	content, err := afero.ReadFile(fs, path)
+	if err != nil {
+		return err
+	}
+
+	locked, err := setSHAs(content, shas)
+	if err != nil {
+		return err
+	}
+
+	return writeFile(fs, path, locked)
+}
+
+// writeFile replaces the content of the file 'path' with 'data'. The data is
+// written to a temporary file next to it, which is then renamed to 'path', so
+// that the file is never left partially written.
+func writeFile(fs afero.Fs, path string, data []byte) (err error) {
+	info, err := fs.Stat(path)
+	if err != nil {
+		return err
+	}
+
+	f, err := afero.TempFile(fs, filepath.Dir(path), fmt.Sprintf(".%s.", filepath.Base(path)))
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		if err != nil {
+			_ = f.Close()
+			_ = fs.Remove(f.Name())
+		}
+	}()
+
+	if err := fs.Chmod(f.Name(), info.Mode()); err != nil {
+		return err
+	}
+
+	if _, err := f.Write(data); err != nil {
+		return err
+	}
+
+	if err := f.Sync(); err != nil {
+		return err
+	}
+
+	if err := f.Close(); err != nil {
+		return err
+	}
+
+	return fs.Rename(f.Name(), path)
+}
+
+// setSHAs sets the 'sha' fields of the Git references of the versions in the
+// YAML document 'content'. Versions are identified by their index in 'shas'.
+func setSHAs(content []byte, shas map[int]string) ([]byte, error) {
+	var doc yaml.Node
+
+	if err := yaml.Unmarshal(content, &doc); err != nil {
+		return nil, err
+	}
+
+	if doc.Kind != yaml.DocumentNode || len(doc.Content) != 1 {
+		return nil, errors.New("expected a single YAML document")
+	}
+
+	versions := mappingValue(doc.Content[0], "versions")
+	if versions == nil || versions.Kind != yaml.SequenceNode {
+		return nil, errors.New("expected a list of versions")
+	}
+
+	for i, sha := range shas {
+		if i >= len(versions.Content) {
+			return nil, fmt.Errorf("version %d not found", i+1)
+		}
+
+		git := mappingValue(versions.Content[i], "git")
+		if git == nil || git.Kind != yaml.MappingNode {
+			return nil, fmt.Errorf("version %d doesn't reference Git", i+1)
+		}
+
+		if node := mappingValue(git, "sha"); node != nil {
+			node.Tag = "!!str"
+			node.Value = sha
+
+			continue
+		}
+
+		git.Content = append(git.Content,
+			&yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: "sha"},
+			&yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: sha, Style: yaml.DoubleQuotedStyle})
+	}
+
+	var buf bytes.Buffer
+
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+
+	if err := encoder.Encode(&doc); err != nil {
+		return nil, err
+	}
+
+	if err := encoder.Close(); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// mappingValue returns the value of 'key' in a mapping node, or nil if 'node'
+// isn't a mapping or doesn't contain 'key'.
+func mappingValue(node *yaml.Node, key string) *yaml.Node {
+	if node.Kind != yaml.MappingNode {
+		return nil
+	}
+
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i+1]
+		}
+	}
+
+	return nil
+}
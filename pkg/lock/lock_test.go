@@ -0,0 +1,107 @@
+package lock
+
+import (
+	"errors"
+	"os"
+	"testing"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSetSHAs(t *testing.T) {
+	content := `apiVersion: index.kudo.dev/v1alpha1
+kind: Operator
+name: foo
+gitSources:
+  - name: src
+    url: https://example.org/foo.git
+versions:
+  # The first release.
+  - operatorVersion: "1.0.0"
+    git:
+      source: src
+      directory: operator
+      tag: v1.0.0
+  - operatorVersion: "1.1.0"
+    url: https://example.org/foo-1.1.0.tgz
+  - operatorVersion: "2.0.0"
+    git:
+      source: src
+      directory: operator
+      tag: v2.0.0
+      sha: 0000000000000000000000000000000000000000
+`
+
+	expected := `apiVersion: index.kudo.dev/v1alpha1
+kind: Operator
+name: foo
+gitSources:
+  - name: src
+    url: https://example.org/foo.git
+versions:
+  # The first release.
+  - operatorVersion: "1.0.0"
+    git:
+      source: src
+      directory: operator
+      tag: v1.0.0
+      sha: "1111111111111111111111111111111111111111"
+  - operatorVersion: "1.1.0"
+    url: https://example.org/foo-1.1.0.tgz
+  - operatorVersion: "2.0.0"
+    git:
+      source: src
+      directory: operator
+      tag: v2.0.0
+      sha: "2222222222222222222222222222222222222222"
+`
+
+	locked, err := setSHAs([]byte(content), map[int]string{
+		0: "1111111111111111111111111111111111111111",
+		2: "2222222222222222222222222222222222222222",
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, expected, string(locked))
+
+	_, err = setSHAs([]byte(content), map[int]string{1: "1111111111111111111111111111111111111111"})
+	assert.EqualError(t, err, "version 2 doesn't reference Git")
+}
+
+// failingRenameFs fails all renames.
+type failingRenameFs struct {
+	afero.Fs
+}
+
+func (fs failingRenameFs) Rename(oldname, newname string) error {
+	return errors.New("rename failed")
+}
+
+func TestWriteFile(t *testing.T) {
+	fs := afero.NewMemMapFs()
+
+	assert.NoError(t, fs.MkdirAll("/operators", 0755))
+	assert.NoError(t, afero.WriteFile(fs, "/operators/foo.yaml", []byte("name: foo"), 0640))
+
+	// The file is unchanged if it can't be replaced.
+	assert.Error(t, writeFile(failingRenameFs{Fs: fs}, "/operators/foo.yaml", []byte("name: bar")))
+
+	content, err := afero.ReadFile(fs, "/operators/foo.yaml")
+	assert.NoError(t, err)
+	assert.Equal(t, "name: foo", string(content))
+
+	assert.NoError(t, writeFile(fs, "/operators/foo.yaml", []byte("name: bar")))
+
+	content, err = afero.ReadFile(fs, "/operators/foo.yaml")
+	assert.NoError(t, err)
+	assert.Equal(t, "name: bar", string(content))
+
+	info, err := fs.Stat("/operators/foo.yaml")
+	assert.NoError(t, err)
+	assert.Equal(t, os.FileMode(0640), info.Mode().Perm())
+
+	// No temporary files are left behind.
+	entries, err := afero.ReadDir(fs, "/operators")
+	assert.NoError(t, err)
+	assert.Len(t, entries, 1)
+}
@@ -0,0 +1,138 @@
+package prune
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/kudobuilder/kitt/pkg/internal/reference"
+	"github.com/kudobuilder/kitt/pkg/internal/repo"
+	"github.com/kudobuilder/kitt/pkg/internal/resolver"
+	"github.com/kudobuilder/kitt/pkg/loader"
+)
+
+// Prune removes operator packages from a repository that aren't referenced by
+// any of the loaded operators. References are resolved like by 'update', so
+// that packages are matched by the name and versions of their package
+// metadata, even if the references set different versions. Sources are
+// retrieved through 'cache' and up to 'parallelism' operator versions are
+// resolved concurrently.
+// Nothing is removed if any reference can't be resolved.
+// If 'dryRun' is set, the packages that would be removed are only written to
+// 'out' and the repository isn't changed.
+func Prune(
+	ctx context.Context,
+	operatorLoader loader.OperatorLoader,
+	repoPath string,
+	repoURL string,
+	cache *resolver.Cache,
+	parallelism int,
+	dryRun bool,
+	out io.Writer,
+) (err error) {
+	syncedRepo, err := repo.OpenSyncedRepo(repoPath, repoURL)
+	if err != nil {
+		return err
+	}
+
+	operators, err := operatorLoader.Apply()
+	if err != nil {
+		return fmt.Errorf("failed to load operator configurations: %v", err)
+	}
+
+	referenced, err := referencedPackages(ctx, reference.List(operators), cache, parallelism)
+	if err != nil {
+		return err
+	}
+
+	// Removals are staged and only committed if all of them succeed.
+	defer func() {
+		if err != nil || dryRun {
+			syncedRepo.Rollback()
+		}
+	}()
+
+	for _, pkg := range syncedRepo.Packages() {
+		if isReferenced(pkg, referenced) {
+			continue
+		}
+
+		tarball, err := syncedRepo.Remove(pkg)
+		if err != nil {
+			return fmt.Errorf("failed to remove operator %q from the repository: %v", pkg.String(), err)
+		}
+
+		if dryRun {
+			if _, err := fmt.Fprintf(out, "would remove %s\n", tarball); err != nil {
+				return err
+			}
+
+			continue
+		}
+
+		log.WithField("operator", pkg.OperatorName).
+			WithField("repository", repoURL).
+			WithField("tarball", tarball).
+			Info("Removing operator from the repository")
+	}
+
+	if dryRun {
+		return nil
+	}
+
+	if err := syncedRepo.Commit(); err != nil {
+		return fmt.Errorf("failed to update index of repository %q: %v", repoPath, err)
+	}
+
+	return nil
+}
+
+// referencedPackages resolves the packages of 'references'. The metadata of a
+// package may differ from its reference, e.g. if the reference doesn't set an
+// app version, and it's the package metadata that is added to repositories.
+func referencedPackages(
+	ctx context.Context,
+	references []reference.Reference,
+	cache *resolver.Cache,
+	parallelism int,
+) (packages []repo.Package, err error) {
+	err = reference.Resolve(
+		ctx,
+		references,
+		cache,
+		parallelism,
+		func(r reference.Reference) {
+			log.WithField("operator", r.Operator.Name).
+				WithField("version", r.Version.Version()).
+				Info("Resolving operator")
+		},
+		func(r reference.Reference, resolved reference.Resolved) error {
+			// Without the package metadata we can't tell which package of the
+			// repository is referenced.
+			if resolved.Err != nil {
+				return fmt.Errorf("failed to resolve version %q of operator %q: %v",
+					r.Version.Version(), r.Operator.Name, resolved.Err)
+			}
+
+			packages = append(packages, resolved.Package)
+
+			return nil
+		})
+	if err != nil {
+		return nil, err
+	}
+
+	return packages, nil
+}
+
+func isReferenced(pkg repo.Package, referenced []repo.Package) bool {
+	for _, r := range referenced {
+		if pkg.Equal(r) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,151 @@
+package prune
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/kudobuilder/kitt/pkg/internal/apis/operator"
+	"github.com/kudobuilder/kitt/pkg/internal/repo"
+	"github.com/kudobuilder/kitt/pkg/internal/resolver"
+	"github.com/kudobuilder/kitt/pkg/loader/loadertest"
+)
+
+// newTestPackage writes an operator package with the versions 'operatorVersion'
+// and 'appVersion' to a temporary directory and returns its path.
+func newTestPackage(t *testing.T, operatorVersion, appVersion string) string {
+	pkgDir := t.TempDir()
+
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(pkgDir, "operator.yaml"), []byte(fmt.Sprintf(`name: foo
+operatorVersion: %q
+appVersion: %q
+`, operatorVersion, appVersion)), 0644))
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(pkgDir, "params.yaml"), []byte{}, 0644))
+
+	return pkgDir
+}
+
+// newTestRepo creates a repository containing the packages in 'pkgDirs' and
+// returns its path.
+func newTestRepo(t *testing.T, pkgDirs ...string) string {
+	repoDir := t.TempDir()
+
+	syncedRepo, err := repo.OpenSyncedRepo(repoDir, "")
+	assert.NoError(t, err)
+
+	for _, pkgDir := range pkgDirs {
+		pkg, err := repo.NewPackage(afero.NewBasePathFs(afero.NewOsFs(), pkgDir))
+		assert.NoError(t, err)
+
+		_, err = syncedRepo.Add(pkg)
+		assert.NoError(t, err)
+	}
+
+	assert.NoError(t, syncedRepo.Commit())
+
+	return repoDir
+}
+
+func TestPrune(t *testing.T) {
+	kept := newTestPackage(t, "1.0.0", "2.0.0")
+	removed := newTestPackage(t, "2.0.0", "2.0.0")
+
+	// The reference doesn't set the app version of its package.
+	operators := loadertest.Operators{
+		{
+			Name:     "foo",
+			Versions: []operator.Version{{OperatorVersion: "1.0.0", Path: &kept}},
+		},
+	}
+
+	tests := []struct {
+		name     string
+		dryRun   bool
+		expected []string
+	}{
+		{
+			name:     "dry run",
+			dryRun:   true,
+			expected: []string{"foo-2.0.0_1.0.0.tgz", "foo-2.0.0_2.0.0.tgz"},
+		},
+		{
+			name:     "prune",
+			dryRun:   false,
+			expected: []string{"foo-2.0.0_1.0.0.tgz"},
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+
+		t.Run(test.name, func(t *testing.T) {
+			repoDir := newTestRepo(t, kept, removed)
+
+			out := &bytes.Buffer{}
+
+			cache, err := resolver.NewCache(resolver.Options{})
+			assert.NoError(t, err)
+
+			err = Prune(context.Background(), operators, repoDir, "", cache, 1, test.dryRun, out)
+			assert.NoError(t, err)
+			assert.NoError(t, cache.Remove())
+
+			if test.dryRun {
+				assert.Equal(t, "would remove foo-2.0.0_2.0.0.tgz\n", out.String())
+			} else {
+				assert.Empty(t, out.String())
+			}
+
+			for _, tarball := range []string{"foo-2.0.0_1.0.0.tgz", "foo-2.0.0_2.0.0.tgz"} {
+				_, err := os.Stat(filepath.Join(repoDir, tarball))
+				assert.Equal(t, contains(test.expected, tarball), err == nil, tarball)
+			}
+
+			syncedRepo, err := repo.OpenSyncedRepo(repoDir, "")
+			assert.NoError(t, err)
+			assert.Len(t, syncedRepo.Packages(), len(test.expected))
+		})
+	}
+}
+
+func TestPruneUnresolvedReference(t *testing.T) {
+	pkgDir := newTestPackage(t, "1.0.0", "2.0.0")
+	repoDir := newTestRepo(t, pkgDir)
+
+	missing := filepath.Join(t.TempDir(), "missing")
+
+	operators := loadertest.Operators{
+		{
+			Name:     "foo",
+			Versions: []operator.Version{{OperatorVersion: "2.0.0", Path: &missing}},
+		},
+	}
+
+	cache, err := resolver.NewCache(resolver.Options{})
+	assert.NoError(t, err)
+
+	err = Prune(context.Background(), operators, repoDir, "", cache, 1, false, &bytes.Buffer{})
+	assert.Error(t, err)
+	assert.NoError(t, cache.Remove())
+
+	syncedRepo, err := repo.OpenSyncedRepo(repoDir, "")
+	assert.NoError(t, err)
+	assert.Len(t, syncedRepo.Packages(), 1)
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,76 @@
+package update
+
+import (
+	"fmt"
+	"io"
+	"text/tabwriter"
+
+	"github.com/kudobuilder/kitt/pkg/internal/output"
+)
+
+// Action describes how an update changes a repository for a single operator
+// version.
+type Action string
+
+const (
+	// ActionAdd adds an operator version that isn't in the repository.
+	ActionAdd Action = "add"
+
+	// ActionSkip skips an operator version that is already in the
+	// repository.
+	ActionSkip Action = "skip"
+
+	// ActionOverwrite replaces an operator version that is already in the
+	// repository, because the update is forced.
+	ActionOverwrite Action = "overwrite"
+)
+
+// Plan lists the changes of an update. Changes are in the order of the
+// operator references.
+type Plan struct {
+	DryRun  bool     `json:"dryRun" yaml:"dryRun"`
+	Changes []Change `json:"changes" yaml:"changes"`
+}
+
+// Change describes the action for a single operator version in a repository.
+type Change struct {
+	// Repository is the path of the repository or the URL of the OCI
+	// registry namespace.
+	Repository string `json:"repository" yaml:"repository"`
+
+	Operator string `json:"operator" yaml:"operator"`
+	Version  string `json:"version" yaml:"version"`
+	Package  string `json:"package" yaml:"package"`
+	Action   Action `json:"action" yaml:"action"`
+
+	// Commit is the SHA of the Git commit the package has been checked out
+	// from. It is only set for Git references.
+	Commit string `json:"commit,omitempty" yaml:"commit,omitempty"`
+
+	// Tarball, Digest and PreviousDigest are only set for added and
+	// overwritten operator versions.
+	Tarball        string `json:"tarball,omitempty" yaml:"tarball,omitempty"`
+	Digest         string `json:"digest,omitempty" yaml:"digest,omitempty"`
+	PreviousDigest string `json:"previousDigest,omitempty" yaml:"previousDigest,omitempty"`
+
+	// Changed is true if the package content differs from the content in
+	// the repository.
+	Changed bool `json:"changed" yaml:"changed"`
+}
+
+// Write prints the plan in one of the formats "text", "json" or "yaml".
+func (p Plan) Write(w io.Writer, format string) error {
+	return output.Write(w, format, p, func(w io.Writer) error {
+		tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+
+		fmt.Fprintln(tw, "ACTION\tOPERATOR\tVERSION\tPACKAGE\tCHANGED\tCOMMIT\tREPOSITORY")
+
+		for _, change := range p.Changes {
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%t\t%s\t%s\n",
+				change.Action, change.Operator, change.Version, change.Package, change.Changed, change.Commit,
+				change.Repository)
+		}
+
+		return tw.Flush()
+	})
+}
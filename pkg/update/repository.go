@@ -0,0 +1,46 @@
+package update
+
+import (
+	"context"
+
+	"github.com/kudobuilder/kitt/pkg/internal/repo"
+)
+
+// repository is a destination of operator packages. Packages are staged by
+// 'Add' and published by 'Commit'.
+type repository interface {
+	Contains(ctx context.Context, pkg repo.Package) (bool, error)
+	Check(ctx context.Context, pkg repo.Package) (repo.Addition, error)
+	Add(ctx context.Context, pkg repo.Package) (repo.Addition, error)
+	Commit(ctx context.Context) error
+	Rollback()
+}
+
+// target is a repository updated by 'Update'.
+type target struct {
+	repository
+
+	// name identifies the repository in plans and logs.
+	name string
+}
+
+// indexRepository adapts a repository with an index file to 'repository'.
+type indexRepository struct {
+	*repo.SyncedRepo
+}
+
+func (r indexRepository) Contains(_ context.Context, pkg repo.Package) (bool, error) {
+	return r.SyncedRepo.Contains(pkg), nil
+}
+
+func (r indexRepository) Check(_ context.Context, pkg repo.Package) (repo.Addition, error) {
+	return r.SyncedRepo.Check(pkg)
+}
+
+func (r indexRepository) Add(_ context.Context, pkg repo.Package) (repo.Addition, error) {
+	return r.SyncedRepo.Add(pkg)
+}
+
+func (r indexRepository) Commit(_ context.Context) error {
+	return r.SyncedRepo.Commit()
+}
@@ -5,111 +5,212 @@ import (
 	"fmt"
 
 	log "github.com/sirupsen/logrus"
-	"github.com/spf13/afero"
 
-	"github.com/kudobuilder/kitt/pkg/internal/apis/operator"
+	"github.com/kudobuilder/kitt/pkg/internal/reference"
 	"github.com/kudobuilder/kitt/pkg/internal/repo"
 	"github.com/kudobuilder/kitt/pkg/internal/resolver"
 	"github.com/kudobuilder/kitt/pkg/loader"
 )
 
 // Update resolves a list of operators and adds them to a repository.
+// Sources are retrieved through 'cache', which may already contain the
+// sources of discovered versions. Up to 'parallelism' operator versions are
+// resolved concurrently.
+// If 'ociRepoURL' isn't empty, packages are also pushed as artifacts to this
+// OCI registry namespace, e.g. "oci://registry.example.org/kudo", with the
+// OCI client of 'cache'. Artifacts are pushed
+// before the index file is written.
+// The returned plan lists the changes applied to the repositories. If 'dryRun'
+// is set, all changes are computed but the repositories aren't changed.
 func Update(
 	ctx context.Context,
 	operatorLoader loader.OperatorLoader,
 	repoPath string,
 	repoURL string,
+	ociRepoURL string,
+	cache *resolver.Cache,
+	parallelism int,
 	force bool,
-) error {
-	repoFs := afero.NewBasePathFs(afero.NewOsFs(), repoPath)
+	dryRun bool,
+) (plan Plan, err error) {
+	plan.DryRun = dryRun
 
-	isDir, err := afero.IsDir(repoFs, "")
+	syncedRepo, err := repo.OpenSyncedRepo(repoPath, repoURL)
 	if err != nil {
-		return fmt.Errorf("failed to open repository path %q: %v", repoPath, err)
+		return plan, err
 	}
 
-	if !isDir {
-		return fmt.Errorf("repository path %q is not a directory", repoPath)
-	}
+	targets := []target{{repository: indexRepository{syncedRepo}, name: repoPath}}
 
-	syncedRepo, err := repo.NewSyncedRepo(repoFs, repoURL)
-	if err != nil {
-		return fmt.Errorf("failed to open repository %q: %v", repoPath, err)
+	if ociRepoURL != "" {
+		ociRepo, err := repo.NewOCIRepo(cache.OCIClient(), ociRepoURL)
+		if err != nil {
+			return plan, err
+		}
+
+		targets = append(targets, target{repository: ociRepo, name: ociRepoURL})
 	}
 
+	// Packages are only visible in the repositories once all of them have been
+	// added successfully.
+	defer func() {
+		if err != nil {
+			for _, t := range targets {
+				t.Rollback()
+			}
+		}
+	}()
+
 	operators, err := operatorLoader.Apply()
 	if err != nil {
-		return fmt.Errorf("failed to load operator configurations: %v", err)
+		return plan, fmt.Errorf("failed to load operator configurations: %v", err)
 	}
 
-	for _, operator := range operators {
-		for _, version := range operator.Versions {
-			log.WithField("operator", operator.Name).
-				WithField("version", version.Version()).
+	// Packages are resolved concurrently, but added to the repository
+	// one after another in the order of the references.
+	err = reference.Resolve(
+		ctx,
+		reference.List(operators),
+		cache,
+		parallelism,
+		func(r reference.Reference) {
+			log.WithField("operator", r.Operator.Name).
+				WithField("version", r.Version.Version()).
 				WithField("repository", repoURL).
 				WithField("path", repoPath).
 				Info("Updating operator")
-
-			if err := updateOperator(ctx, operator, version, syncedRepo, force); err != nil {
+		},
+		func(r reference.Reference, resolved reference.Resolved) error {
+			changes, err := updateOperator(ctx, r, resolved, targets, force, dryRun)
+			if err != nil {
 				return err
 			}
+
+			plan.Changes = append(plan.Changes, changes...)
+
+			return nil
+		})
+	if err != nil {
+		return plan, err
+	}
+
+	if dryRun {
+		return plan, nil
+	}
+
+	// Once all packages have been added, artifacts are pushed and then the
+	// index file is written. The index is only written if all artifacts have
+	// been pushed, so that it never references packages missing in the
+	// registry.
+	for i := len(targets) - 1; i >= 0; i-- {
+		if err := targets[i].Commit(ctx); err != nil {
+			if i < len(targets)-1 {
+				return plan, fmt.Errorf("failed to update repository %q after updating repository %q: %v",
+					targets[i].name, targets[i+1].name, err)
+			}
+
+			return plan, fmt.Errorf("failed to update repository %q: %v", targets[i].name, err)
 		}
 	}
 
-	return nil
+	return plan, nil
 }
 
 func updateOperator(
 	ctx context.Context,
-	operator operator.Operator,
-	version operator.Version,
-	syncedRepo *repo.SyncedRepo,
+	r reference.Reference,
+	resolved reference.Resolved,
+	targets []target,
 	force bool,
-) (err error) {
-	operatorName := fmt.Sprintf("%s-%s", operator.Name, version.Version())
+	dryRun bool,
+) (changes []Change, err error) {
+	if resolved.Err != nil {
+		return nil, resolved.Err
+	}
 
-	resolver, err := resolver.New(operator, version)
-	if err != nil {
-		return fmt.Errorf("failed to resolve operator %q: %v", operatorName, err)
+	for _, t := range targets {
+		change, err := addOperator(ctx, r, resolved.Package, t, force, dryRun)
+		if err != nil {
+			return nil, err
+		}
+
+		changes = append(changes, change)
 	}
 
-	pkgFs, remover, err := resolver.Resolve(ctx)
-	if err != nil {
-		return fmt.Errorf("failed to resolve operator %q: %v", operatorName, err)
+	return changes, nil
+}
+
+func addOperator(
+	ctx context.Context,
+	r reference.Reference,
+	pkg repo.Package,
+	t target,
+	force bool,
+	dryRun bool,
+) (Change, error) {
+	change := Change{
+		Repository: t.name,
+		Operator:   r.Operator.Name,
+		Version:    r.Version.Version(),
+		Package:    pkg.String(),
+		Action:     ActionSkip,
+		Commit:     pkg.Commit,
 	}
 
-	// The package resolver created a temporary directory for the package file system.
-	// We remove it once we no longer need it.
-	defer func() {
-		if rerr := remover(); rerr != nil {
-			err = fmt.Errorf("failed to remove temporary directory of operator %q: %v", operatorName, rerr)
-		}
-	}()
+	logger := log.WithField("operator", r.Operator.Name).
+		WithField("version", r.Version.Version()).
+		WithField("repository", t.name)
 
-	pkg, err := repo.NewPackage(pkgFs)
+	contains, err := t.Contains(ctx, pkg)
 	if err != nil {
-		return fmt.Errorf("failed to extract package version of operator %q: %v", operatorName, err)
+		return Change{}, fmt.Errorf("failed to look up operator %q in repository %q: %v", pkg.String(), t.name, err)
 	}
 
-	contains := syncedRepo.Contains(pkg)
+	if contains && !force {
+		logger.Info("Operator is already in the repository")
 
-	if !contains || force {
-		pkgName, err := syncedRepo.Add(pkg)
-		if err != nil {
-			return fmt.Errorf("failed to add operator %q to the repository: %v", pkg.String(), err)
-		}
+		return change, nil
+	}
 
-		log.WithField("operator", operator.Name).
-			WithField("version", version.Version()).
-			WithField("repository", syncedRepo.URL).
-			WithField("tarball", pkgName).
-			Info("Added operator to the repository")
+	var addition repo.Addition
+
+	if dryRun {
+		addition, err = t.Check(ctx, pkg)
+	} else {
+		addition, err = t.Add(ctx, pkg)
+	}
+
+	if err != nil {
+		return Change{}, fmt.Errorf("failed to add operator %q to repository %q: %v", pkg.String(), t.name, err)
+	}
+
+	change.Tarball = addition.Tarball
+	change.Digest = addition.Digest
+	change.PreviousDigest = addition.PreviousDigest
+	change.Changed = addition.Changed()
+
+	if addition.Replaced() {
+		change.Action = ActionOverwrite
 	} else {
-		log.WithField("operator", operator.Name).
-			WithField("version", version.Version()).
-			WithField("repository", syncedRepo.URL).
-			Info("Operator is already in the repository")
+		change.Action = ActionAdd
+	}
+
+	if dryRun {
+		return change, nil
+	}
+
+	logger = logger.WithField("tarball", addition.Tarball).
+		WithField("digest", addition.Digest)
+
+	switch {
+	case !addition.Replaced():
+		logger.Info("Added operator to the repository")
+	case addition.Changed():
+		logger.WithField("previousDigest", addition.PreviousDigest).
+			Warn("Replaced operator in the repository with different content")
+	default:
+		logger.Info("Operator in the repository is unchanged")
 	}
 
-	return nil
+	return change, nil
 }
@@ -0,0 +1,109 @@
+package update
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/kudobuilder/kitt/pkg/internal/apis/operator"
+	"github.com/kudobuilder/kitt/pkg/internal/config"
+	"github.com/kudobuilder/kitt/pkg/internal/oci/ocitest"
+	"github.com/kudobuilder/kitt/pkg/internal/resolver"
+	"github.com/kudobuilder/kitt/pkg/loader/loadertest"
+)
+
+// newTestOperators writes an operator package to a temporary directory and
+// returns an operator referencing it.
+func newTestOperators(t *testing.T) loadertest.Operators {
+	pkgDir := t.TempDir()
+
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(pkgDir, "operator.yaml"), []byte(`name: foo
+operatorVersion: "1.0.0"
+appVersion: "2.0.0"
+`), 0644))
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(pkgDir, "params.yaml"), []byte{}, 0644))
+
+	return loadertest.Operators{
+		{
+			Name:     "foo",
+			Versions: []operator.Version{{OperatorVersion: "1.0.0", Path: &pkgDir}},
+		},
+	}
+}
+
+func TestUpdateOCI(t *testing.T) {
+	registry := ocitest.NewRegistry()
+	registry.Token = "token"
+	registry.Username = "kitt"
+	registry.Password = "secret"
+
+	defer registry.Close()
+
+	assert.NoError(t, os.Setenv("KITT_TEST_REGISTRY_PASSWORD", "secret"))
+
+	defer os.Unsetenv("KITT_TEST_REGISTRY_PASSWORD")
+
+	options := resolver.Options{
+		Config: config.Config{
+			Hosts: []config.Host{
+				{
+					Host: registry.Host(),
+					Auth: operator.URLAuth{
+						Username: "kitt",
+						Password: &operator.Credential{Env: "KITT_TEST_REGISTRY_PASSWORD"},
+					},
+				},
+			},
+		},
+	}
+
+	cache, err := resolver.NewCache(options)
+	assert.NoError(t, err)
+
+	repoDir := t.TempDir()
+
+	_, err = Update(
+		context.Background(), newTestOperators(t), repoDir, "", "oci://"+registry.Host()+"/kudo", cache, 1, false, false)
+	assert.NoError(t, err)
+	assert.NoError(t, cache.Remove())
+
+	assert.FileExists(t, filepath.Join(repoDir, "index.yaml"))
+	assert.Equal(t, 2, registry.Uploads())
+}
+
+func TestUpdateOCIPushFailure(t *testing.T) {
+	// The registry doesn't contain any manifests and fails all uploads.
+	registry := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.Method == http.MethodHead {
+			http.NotFound(w, req)
+			return
+		}
+
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+
+	defer registry.Close()
+
+	repoDir := t.TempDir()
+
+	ociRepoURL := "oci://" + strings.TrimPrefix(registry.URL, "http://") + "/kudo"
+
+	cache, err := resolver.NewCache(resolver.Options{})
+	assert.NoError(t, err)
+
+	_, err = Update(context.Background(), newTestOperators(t), repoDir, "", ociRepoURL, cache, 1, false, false)
+	assert.Error(t, err)
+	assert.NoError(t, cache.Remove())
+
+	// The index isn't written if pushing fails.
+	entries, err := ioutil.ReadDir(repoDir)
+	assert.NoError(t, err)
+	assert.Empty(t, entries)
+}
@@ -0,0 +1,161 @@
+package validate
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/kudobuilder/kitt/pkg/internal/output"
+	"github.com/kudobuilder/kitt/pkg/internal/reference"
+	"github.com/kudobuilder/kitt/pkg/internal/validation"
+)
+
+// Severity of a validation issue.
+type Severity string
+
+const (
+	// SeverityWarning is the severity of issues that only fail a strict
+	// validation.
+	SeverityWarning Severity = "warning"
+
+	// SeverityError is the severity of issues that fail a validation.
+	SeverityError Severity = "error"
+)
+
+// Report lists the validation results of all operators. Operators and their
+// versions are in the order of the operator references.
+type Report struct {
+	Operators []OperatorReport `json:"operators" yaml:"operators"`
+	Summary   Summary          `json:"summary" yaml:"summary"`
+}
+
+// Summary counts the issues of all operator versions.
+type Summary struct {
+	// Versions is the number of validated operator versions.
+	Versions int `json:"versions" yaml:"versions"`
+
+	// Failed is the number of operator versions that failed validation.
+	// With strict validation, warnings fail validation as well.
+	Failed int `json:"failed" yaml:"failed"`
+
+	Errors   int `json:"errors" yaml:"errors"`
+	Warnings int `json:"warnings" yaml:"warnings"`
+}
+
+// OperatorReport lists the validation results of the versions of an operator.
+type OperatorReport struct {
+	Name string `json:"name" yaml:"name"`
+
+	// File is the path of the operator reference.
+	File     string          `json:"file,omitempty" yaml:"file,omitempty"`
+	Versions []VersionReport `json:"versions" yaml:"versions"`
+}
+
+// VersionReport lists the issues of an operator version.
+type VersionReport struct {
+	Version string  `json:"version" yaml:"version"`
+	Package string  `json:"package" yaml:"package"`
+	Issues  []Issue `json:"issues" yaml:"issues"`
+}
+
+// Issue is a single warning or error of an operator version.
+type Issue struct {
+	Severity Severity `json:"severity" yaml:"severity"`
+	Rule     string   `json:"rule" yaml:"rule"`
+	Message  string   `json:"message" yaml:"message"`
+}
+
+// add appends the validation result of an operator version to the report.
+func (r *Report) add(ref reference.Reference, result validation.Result) {
+	operatorName := ref.Operator.Name
+	file := ref.Operator.File
+
+	var operatorReport *OperatorReport
+
+	// References of an operator are validated one after another, hence
+	// versions of the same operator are always appended to the last report.
+	if n := len(r.Operators); n > 0 && r.Operators[n-1].Name == operatorName && r.Operators[n-1].File == file {
+		operatorReport = &r.Operators[n-1]
+	} else {
+		r.Operators = append(r.Operators, OperatorReport{Name: operatorName, File: file})
+		operatorReport = &r.Operators[len(r.Operators)-1]
+	}
+
+	versionReport := VersionReport{
+		Version: ref.Version.Version(),
+		Package: fmt.Sprintf("%s-%s", operatorName, ref.Version.Version()),
+		Issues:  []Issue{},
+	}
+
+	for _, warning := range result.Warnings {
+		versionReport.Issues = append(versionReport.Issues, Issue{
+			Severity: SeverityWarning,
+			Rule:     warning.Rule,
+			Message:  warning.Message,
+		})
+	}
+
+	for _, error := range result.Errors {
+		versionReport.Issues = append(versionReport.Issues, Issue{
+			Severity: SeverityError,
+			Rule:     error.Rule,
+			Message:  error.Message,
+		})
+	}
+
+	operatorReport.Versions = append(operatorReport.Versions, versionReport)
+}
+
+// summarize counts the issues of all operator versions.
+func (r *Report) summarize(strict bool) {
+	r.Summary = Summary{}
+
+	for _, operatorReport := range r.Operators {
+		for _, versionReport := range operatorReport.Versions {
+			failed := false
+
+			for _, issue := range versionReport.Issues {
+				switch issue.Severity {
+				case SeverityError:
+					r.Summary.Errors++
+					failed = true
+				case SeverityWarning:
+					r.Summary.Warnings++
+					failed = failed || strict
+				}
+			}
+
+			r.Summary.Versions++
+
+			if failed {
+				r.Summary.Failed++
+			}
+		}
+	}
+}
+
+// Write prints the report in one of the formats "text", "json" or "yaml".
+// The "text" format lists issues followed by the summary.
+func (r Report) Write(w io.Writer, format string) error {
+	return output.Write(w, format, r, func(w io.Writer) error {
+		for _, operatorReport := range r.Operators {
+			for _, versionReport := range operatorReport.Versions {
+				for _, issue := range versionReport.Issues {
+					location := versionReport.Package
+					if operatorReport.File != "" {
+						location = fmt.Sprintf("%s (%s)", location, operatorReport.File)
+					}
+
+					if _, err := fmt.Fprintf(w, "%s: %s: [%s] %s\n",
+						issue.Severity, location, issue.Rule, issue.Message); err != nil {
+						return err
+					}
+				}
+			}
+		}
+
+		_, err := fmt.Fprintf(w, "%d of %d operator versions failed validation with %d errors and %d warnings\n",
+			r.Summary.Failed, r.Summary.Versions, r.Summary.Errors, r.Summary.Warnings)
+
+		return err
+	})
+}
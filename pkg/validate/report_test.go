@@ -0,0 +1,38 @@
+package validate
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/kudobuilder/kitt/pkg/internal/apis/operator"
+	"github.com/kudobuilder/kitt/pkg/internal/reference"
+	"github.com/kudobuilder/kitt/pkg/internal/validation"
+)
+
+func TestReportSummarize(t *testing.T) {
+	foo := operator.Operator{Name: "foo", File: "foo.yaml"}
+	bar := operator.Operator{Name: "bar", File: "bar.yaml"}
+
+	warning := validation.Result{}
+	warning.AddWarning(validation.RuleAppVersionNotSet, "warning")
+
+	failure := validation.Result{}
+	failure.AddError(validation.RuleResolve, "error")
+	failure.AddWarning(validation.RuleAppVersionNotSet, "warning")
+
+	report := Report{}
+	report.add(reference.Reference{Operator: foo, Version: operator.Version{OperatorVersion: "1.0.0"}}, validation.Result{})
+	report.add(reference.Reference{Operator: foo, Version: operator.Version{OperatorVersion: "2.0.0"}}, warning)
+	report.add(reference.Reference{Operator: bar, Version: operator.Version{OperatorVersion: "1.0.0"}}, failure)
+
+	assert.Len(t, report.Operators, 2)
+	assert.Len(t, report.Operators[0].Versions, 2)
+	assert.Equal(t, "bar-1.0.0", report.Operators[1].Versions[0].Package)
+
+	report.summarize(false)
+	assert.Equal(t, Summary{Versions: 3, Failed: 1, Errors: 1, Warnings: 2}, report.Summary)
+
+	report.summarize(true)
+	assert.Equal(t, Summary{Versions: 3, Failed: 2, Errors: 1, Warnings: 2}, report.Summary)
+}
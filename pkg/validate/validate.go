@@ -3,12 +3,10 @@ package validate
 import (
 	"context"
 	"fmt"
-	"strings"
 
 	log "github.com/sirupsen/logrus"
 
-	"github.com/kudobuilder/kitt/pkg/internal/apis/operator"
-	"github.com/kudobuilder/kitt/pkg/internal/repo"
+	"github.com/kudobuilder/kitt/pkg/internal/reference"
 	"github.com/kudobuilder/kitt/pkg/internal/resolver"
 	"github.com/kudobuilder/kitt/pkg/internal/validation"
 	"github.com/kudobuilder/kitt/pkg/loader"
@@ -18,80 +16,71 @@ import (
 // referenced package. It checks that metadata provided in the reference is
 // consistent with the metadata provided in the referenced package and also
 // verifies all referenced packages.
+// Sources are retrieved through 'cache', which may already contain the
+// sources of discovered versions. Up to 'parallelism' operator versions are
+// resolved concurrently.
+// All operator versions are validated, even if some of them fail. Operator
+// versions that can't be resolved are reported as errors. The returned report
+// contains the results of all operator versions and an error is returned if
+// any of them failed validation.
 func Validate(
 	ctx context.Context,
 	operatorLoader loader.OperatorLoader,
+	cache *resolver.Cache,
+	parallelism int,
 	strict bool,
-) error {
+) (report Report, err error) {
 	operators, err := operatorLoader.Apply()
 	if err != nil {
-		return fmt.Errorf("failed to load operator configurations: %v", err)
+		return report, fmt.Errorf("failed to load operator configurations: %v", err)
 	}
 
-	for _, operator := range operators {
-		for _, version := range operator.Versions {
-			log.WithField("operator", operator.Name).
-				WithField("version", version.Version()).
+	// Packages are resolved concurrently, but validated one after another in
+	// the order of the references.
+	err = reference.Resolve(
+		ctx,
+		reference.List(operators),
+		cache,
+		parallelism,
+		func(r reference.Reference) {
+			log.WithField("operator", r.Operator.Name).
+				WithField("version", r.Version.Version()).
 				Info("Validating operator")
+		},
+		func(r reference.Reference, resolved reference.Resolved) error {
+			validateOperator(r, resolved, &report)
 
-			if err := validateOperator(ctx, operator, version, strict); err != nil {
-				return err
-			}
-		}
-	}
-
-	return nil
-}
-
-func validateOperator(
-	ctx context.Context,
-	operator operator.Operator,
-	version operator.Version,
-	strict bool,
-) (err error) {
-	operatorName := fmt.Sprintf("%s-%s", operator.Name, version.Version())
-
-	resolver, err := resolver.New(operator, version)
+			return nil
+		})
 	if err != nil {
-		return fmt.Errorf("failed to resolve operator %q: %v", operatorName, err)
+		return report, err
 	}
 
-	pkgFs, remover, err := resolver.Resolve(ctx)
-	if err != nil {
-		return fmt.Errorf("failed to resolve operator %q: %v", operatorName, err)
-	}
-
-	// The package resolver created a temporary directory for the package file system.
-	// We remove it once we no longer need it.
-	defer func() {
-		if rerr := remover(); rerr != nil {
-			err = fmt.Errorf("failed to remove temporary directory of operator %q: %v", operatorName, rerr)
-		}
-	}()
+	report.summarize(strict)
 
-	pkg, err := repo.NewPackage(pkgFs)
-	if err != nil {
-		return fmt.Errorf("failed to extract package version of operator %q: %v", operatorName, err)
+	if report.Summary.Failed > 0 {
+		return report, fmt.Errorf(
+			"validation failed for %d of %d operator versions with %d errors and %d warnings",
+			report.Summary.Failed,
+			report.Summary.Versions,
+			report.Summary.Errors,
+			report.Summary.Warnings)
 	}
 
-	validationResult := validation.Validate(operator, version, pkg)
-
-	var warnings, errors string
+	return report, nil
+}
 
-	if strict {
-		errors = strings.Join(append(validationResult.Warnings, validationResult.Errors...), "\n")
-	} else {
-		warnings = strings.Join(validationResult.Warnings, "\n")
-		errors = strings.Join(validationResult.Errors, "\n")
-	}
+func validateOperator(r reference.Reference, resolved reference.Resolved, report *Report) {
+	// Operator versions that can't be resolved are reported like any other
+	// failure, so that the remaining versions are still validated.
+	if resolved.Err != nil {
+		result := validation.Result{}
+		result.AddError(validation.RuleResolve, resolved.Err.Error())
 
-	if warnings != "" {
-		fmt.Printf("validation warnings for operator %q:\n%s", operatorName, warnings)
-	}
+		report.add(r, result)
 
-	if errors != "" {
-		return fmt.Errorf("validation failed for operator %q:\n%s", operatorName, errors)
+		return
 	}
 
-	return nil
+	report.add(r, validation.Validate(r.Operator, r.Version, resolved.Package))
 }